@@ -0,0 +1,93 @@
+package comparatorx
+
+import (
+	"cmp"
+	"github.com/Jared-lu/GXT"
+)
+
+// Ordered 按照类型的自然顺序比较
+func Ordered[T cmp.Ordered]() GXT.Comparator[T] {
+	return cmp.Compare[T]
+}
+
+// Reverse 反转比较的结果，比如用来把小根堆变成大根堆
+func Reverse[T any](c GXT.Comparator[T]) GXT.Comparator[T] {
+	return func(src T, dst T) int {
+		return c(dst, src)
+	}
+}
+
+// ComparingBy 先用 key 提取出可比较的字段，再按照字段的自然顺序比较
+func ComparingBy[T any, K cmp.Ordered](key func(T) K) GXT.Comparator[T] {
+	return func(src T, dst T) int {
+		return cmp.Compare(key(src), key(dst))
+	}
+}
+
+// ComparingByFunc 先用 key 提取出字段，再用 c 比较字段
+func ComparingByFunc[T any, K any](key func(T) K, c GXT.Comparator[K]) GXT.Comparator[T] {
+	return func(src T, dst T) int {
+		return c(key(src), key(dst))
+	}
+}
+
+// Then 多字段排序，c1 比较的结果相等时才会用下一个比较器继续比较
+func Then[T any](c1 GXT.Comparator[T], cs ...GXT.Comparator[T]) GXT.Comparator[T] {
+	return func(src T, dst T) int {
+		if res := c1(src, dst); res != 0 {
+			return res
+		}
+		for _, c := range cs {
+			if res := c(src, dst); res != 0 {
+				return res
+			}
+		}
+		return 0
+	}
+}
+
+// NilFirst 比较指针指向的元素，nil 比任何非 nil 的元素都小
+func NilFirst[T any](c GXT.Comparator[T]) GXT.Comparator[*T] {
+	return func(src *T, dst *T) int {
+		switch {
+		case src == nil && dst == nil:
+			return 0
+		case src == nil:
+			return -1
+		case dst == nil:
+			return 1
+		default:
+			return c(*src, *dst)
+		}
+	}
+}
+
+// NilLast 比较指针指向的元素，nil 比任何非 nil 的元素都大
+func NilLast[T any](c GXT.Comparator[T]) GXT.Comparator[*T] {
+	return func(src *T, dst *T) int {
+		switch {
+		case src == nil && dst == nil:
+			return 0
+		case src == nil:
+			return 1
+		case dst == nil:
+			return -1
+		default:
+			return c(*src, *dst)
+		}
+	}
+}
+
+// Slice 按照字典序比较两个切片
+// 逐个比较元素，第一个不相等的元素决定结果；
+// 如果一个切片是另一个切片的前缀，那么短的切片更小
+func Slice[T any](c GXT.Comparator[T]) GXT.Comparator[[]T] {
+	return func(src []T, dst []T) int {
+		for i := 0; i < len(src) && i < len(dst); i++ {
+			if res := c(src[i], dst[i]); res != 0 {
+				return res
+			}
+		}
+		return cmp.Compare(len(src), len(dst))
+	}
+}
@@ -0,0 +1,183 @@
+package comparatorx
+
+import (
+	"github.com/Jared-lu/GXT"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+)
+
+type user struct {
+	name string
+	age  int
+}
+
+func TestOrdered(t *testing.T) {
+	c := Ordered[int]()
+	assert.Equal(t, -1, c(1, 2))
+	assert.Equal(t, 0, c(2, 2))
+	assert.Equal(t, 1, c(3, 2))
+
+	s := Ordered[string]()
+	assert.Equal(t, -1, s("a", "b"))
+}
+
+func TestReverse(t *testing.T) {
+	c := Reverse(Ordered[int]())
+	assert.Equal(t, 1, c(1, 2))
+	assert.Equal(t, 0, c(2, 2))
+	assert.Equal(t, -1, c(3, 2))
+}
+
+func TestComparingBy(t *testing.T) {
+	c := ComparingBy(func(u user) int {
+		return u.age
+	})
+	assert.Equal(t, -1, c(user{age: 10}, user{age: 20}))
+	assert.Equal(t, 0, c(user{name: "a", age: 10}, user{name: "b", age: 10}))
+
+	c = ComparingByFunc(func(u user) string {
+		return u.name
+	}, Reverse(Ordered[string]()))
+	assert.Equal(t, 1, c(user{name: "a"}, user{name: "b"}))
+}
+
+func TestThen(t *testing.T) {
+	byAge := ComparingBy(func(u user) int {
+		return u.age
+	})
+	byName := ComparingBy(func(u user) string {
+		return u.name
+	})
+	testCases := []struct {
+		name string
+		c    GXT.Comparator[user]
+		src  user
+		dst  user
+		want int
+	}{
+		{
+			name: "first key decides",
+			c:    Then(byAge, byName),
+			src:  user{name: "b", age: 10},
+			dst:  user{name: "a", age: 20},
+			want: -1,
+		},
+		{
+			name: "second key decides",
+			c:    Then(byAge, byName),
+			src:  user{name: "b", age: 10},
+			dst:  user{name: "a", age: 10},
+			want: 1,
+		},
+		{
+			name: "equal",
+			c:    Then(byAge, byName),
+			src:  user{name: "a", age: 10},
+			dst:  user{name: "a", age: 10},
+			want: 0,
+		},
+		{
+			name: "only one comparator",
+			c:    Then(byName),
+			src:  user{name: "a", age: 20},
+			dst:  user{name: "a", age: 10},
+			want: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.c(tc.src, tc.dst))
+		})
+	}
+}
+
+func TestNilFirstAndNilLast(t *testing.T) {
+	one, two := 1, 2
+	testCases := []struct {
+		name     string
+		src      *int
+		dst      *int
+		wantNilF int
+		wantNilL int
+	}{
+		{
+			name:     "both nil",
+			wantNilF: 0,
+			wantNilL: 0,
+		},
+		{
+			name:     "src nil",
+			dst:      &one,
+			wantNilF: -1,
+			wantNilL: 1,
+		},
+		{
+			name:     "dst nil",
+			src:      &one,
+			wantNilF: 1,
+			wantNilL: -1,
+		},
+		{
+			name:     "both not nil",
+			src:      &one,
+			dst:      &two,
+			wantNilF: -1,
+			wantNilL: -1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantNilF, NilFirst(Ordered[int]())(tc.src, tc.dst))
+			assert.Equal(t, tc.wantNilL, NilLast(Ordered[int]())(tc.src, tc.dst))
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  []int
+		dst  []int
+		want int
+	}{
+		{
+			name: "both empty",
+			want: 0,
+		},
+		{
+			name: "equal",
+			src:  []int{1, 2, 3},
+			dst:  []int{1, 2, 3},
+			want: 0,
+		},
+		{
+			name: "element decides",
+			src:  []int{1, 3},
+			dst:  []int{1, 2, 3},
+			want: 1,
+		},
+		{
+			name: "prefix is less",
+			src:  []int{1, 2},
+			dst:  []int{1, 2, 3},
+			want: -1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Slice(Ordered[int]())(tc.src, tc.dst))
+		})
+	}
+}
+
+func TestSortWithComparator(t *testing.T) {
+	users := []user{{"b", 20}, {"a", 20}, {"c", 10}}
+	c := Then(Reverse(ComparingBy(func(u user) int {
+		return u.age
+	})), ComparingBy(func(u user) string {
+		return u.name
+	}))
+	slices.SortFunc(users, c)
+	assert.Equal(t, []user{{"a", 20}, {"b", 20}, {"c", 10}}, users)
+}
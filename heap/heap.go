@@ -0,0 +1,229 @@
+package heap
+
+import (
+	"errors"
+	"github.com/Jared-lu/GXT"
+)
+
+var (
+	ErrEmptyHeap       = errors.New("empty heap")
+	ErrInvalidHandle   = errors.New("invalid heap handle")
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
+
+// Handle 堆中元素的句柄，Push 的时候返回
+// 通过句柄可以在元素入堆之后修改它的优先级或者把它从堆里删除
+type Handle[T any] struct {
+	val T
+	// 元素在堆中的下标，元素出堆之后为 -1
+	index int
+	// 元素所属的堆，防止拿着别的堆的句柄来操作
+	heap *Heap[T]
+}
+
+// Value 返回句柄对应的元素
+func (h *Handle[T]) Value() T {
+	return h.val
+}
+
+// Index 返回元素当前在堆中的下标，元素已经不在堆中时返回 -1
+func (h *Handle[T]) Index() int {
+	return h.index
+}
+
+type Heap[T any] struct {
+	// 调用者决定如何比较两个元素之间的大小
+	comparator GXT.Comparator[T]
+	data       []*Handle[T]
+}
+
+// NewHeap 生成小根堆或大根堆，会基于 data 中已有的元素建堆
+// 正常的 Comparator src < dst 返回负数，生成小根堆
+// 利用规则反转，即Comparator src < dst 返回正数时，可以生成大根堆
+// Comparator 返回任意负数都认为 src 更小，不要求一定是 -1
+func NewHeap[T any](data []T, comparator GXT.Comparator[T]) *Heap[T] {
+	heap := &Heap[T]{
+		data:       make([]*Handle[T], 0, cap(data)),
+		comparator: comparator,
+	}
+	for _, val := range data {
+		heap.data = append(heap.data, &Handle[T]{val: val, index: len(heap.data), heap: heap})
+	}
+	heap.heapify()
+	return heap
+}
+
+// Push 入堆，返回元素的句柄
+func (h *Heap[T]) Push(ele T) *Handle[T] {
+	handle := &Handle[T]{val: ele, index: len(h.data), heap: h}
+	h.data = append(h.data, handle)
+	h.siftUp(handle.index)
+	return handle
+}
+
+func (h *Heap[T]) Pop() (T, error) {
+	if h.isEmpty() {
+		var t T
+		return t, ErrEmptyHeap
+	}
+	return h.remove(0), nil
+}
+
+// Peek 返回堆顶元素
+func (h *Heap[T]) Peek() (T, error) {
+	if h.isEmpty() {
+		var t T
+		return t, ErrEmptyHeap
+	}
+	return h.data[0].val, nil
+}
+
+func (h *Heap[T]) Size() int {
+	return len(h.data)
+}
+
+// Update 修改句柄对应元素的值，并且重新调整它在堆中的位置
+func (h *Heap[T]) Update(handle *Handle[T], newVal T) error {
+	if !h.contains(handle) {
+		return ErrInvalidHandle
+	}
+	handle.val = newVal
+	h.fix(handle.index)
+	return nil
+}
+
+// Remove 把句柄对应的元素从堆中删除
+func (h *Heap[T]) Remove(handle *Handle[T]) (T, error) {
+	if !h.contains(handle) {
+		var t T
+		return t, ErrInvalidHandle
+	}
+	return h.remove(handle.index), nil
+}
+
+// Fix 下标为 i 的元素的优先级发生了变化，重新调整它在堆中的位置
+// 通常是元素本身是指针，调用方直接修改了元素的内容之后调用
+func (h *Heap[T]) Fix(i int) error {
+	if i < 0 || i >= len(h.data) {
+		return ErrIndexOutOfRange
+	}
+	h.fix(i)
+	return nil
+}
+
+// Merge 把 other 中的元素全部合并进来，合并之后 other 为空
+// other 中元素的句柄依旧有效，之后要通过当前的堆来操作
+func (h *Heap[T]) Merge(other *Heap[T]) {
+	if other == nil || other == h {
+		return
+	}
+	for _, handle := range other.data {
+		handle.heap = h
+		handle.index = len(h.data)
+		h.data = append(h.data, handle)
+	}
+	other.data = other.data[:0]
+	h.heapify()
+}
+
+func (h *Heap[T]) isEmpty() bool {
+	return len(h.data) == 0
+}
+
+func (h *Heap[T]) contains(handle *Handle[T]) bool {
+	return handle != nil && handle.heap == h && handle.index >= 0 &&
+		handle.index < len(h.data) && h.data[handle.index] == handle
+}
+
+// heapify 通过倒序遍历来建堆，不断往上调整更大的子树
+func (h *Heap[T]) heapify() {
+	for i := h.parent(len(h.data) - 1); i >= 0; i-- {
+		// 堆化除叶节点以外的其他所有节点
+		h.siftDown(i)
+	}
+}
+
+// remove 删除下标为 i 的元素
+// 把它和最后一个元素交换，删掉最后一个元素，再调整交换过来的元素
+func (h *Heap[T]) remove(i int) T {
+	last := len(h.data) - 1
+	handle := h.data[i]
+	h.swap(i, last)
+	h.data[last] = nil
+	h.data = h.data[:last]
+	if i < last {
+		h.fix(i)
+	}
+	handle.index = -1
+	return handle.val
+}
+
+// fix 元素可能变大也可能变小，先尝试向下调整，没有移动再尝试向上调整
+func (h *Heap[T]) fix(i int) {
+	if !h.siftDown(i) {
+		h.siftUp(i)
+	}
+}
+
+func (h *Heap[T]) less(i, j int) bool {
+	return h.comparator(h.data[i].val, h.data[j].val) < 0
+}
+
+func (h *Heap[T]) swap(i, j int) {
+	h.data[i], h.data[j] = h.data[j], h.data[i]
+	h.data[i].index = i
+	h.data[j].index = j
+}
+
+// left 获取左子节点的索引
+func (h *Heap[T]) left(i int) int {
+	return 2*i + 1
+}
+
+// right 获取右子节点的索引
+func (h *Heap[T]) right(i int) int {
+	return 2*i + 2
+}
+
+// parent 获取父节点的索引
+func (h *Heap[T]) parent(i int) int {
+	return (i - 1) / 2
+}
+
+// siftUp 自底向上堆化
+// 子节点和它的父节点进行比较
+func (h *Heap[T]) siftUp(i int) {
+	for i > 0 {
+		parent := h.parent(i)
+		// 与父节点进行比较，小于父节点就说明当前子树要调整
+		if !h.less(i, parent) {
+			break
+		}
+		h.swap(i, parent)
+		// 循环向上堆化
+		i = parent
+	}
+}
+
+// siftDown 自顶向上堆化
+// 根节点和它的左右子节点进行比较，返回元素有没有移动过
+func (h *Heap[T]) siftDown(i int) bool {
+	start := i
+	for {
+		left, right := h.left(i), h.right(i)
+		temp := i
+		if left < h.Size() && h.less(left, temp) {
+			temp = left
+		}
+		if right < h.Size() && h.less(right, temp) {
+			temp = right
+		}
+		if temp == i {
+			break
+		}
+		h.swap(i, temp)
+		// 交换元素后向下堆化，调整交换后的子树
+		i = temp
+	}
+	return i != start
+}
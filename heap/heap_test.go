@@ -41,6 +41,14 @@ func TestHeap(t *testing.T) {
 				return 0
 			},
 		},
+		{
+			name:     "MinHeap - any negative means less",
+			nums:     []int{8, 6, 11, 3, 7, 9, 5},
+			wantNums: []int{3, 6, 5, 8, 7, 9, 11},
+			comparator: func(src int, dst int) int {
+				return src - dst
+			},
+		},
 		{
 			name:     "MaxHeap",
 			nums:     []int{4, 1, 3, 2, 16, 9, 10, 14, 8, 7},
@@ -59,7 +67,7 @@ func TestHeap(t *testing.T) {
 	for _, tc := range tesCases {
 		t.Run(tc.name, func(t *testing.T) {
 			m := NewHeap[int](tc.nums, tc.comparator)
-			assert.Equal(t, tc.wantNums, m.data)
+			assert.Equal(t, tc.wantNums, values(m))
 		})
 	}
 }
@@ -102,6 +110,15 @@ func TestHeap_Push(t *testing.T) {
 				return 0
 			},
 		},
+		{
+			name:     "MinHeap - any negative means less",
+			nums:     []int{8, 6, 11, 3, 7, 9, 5},
+			pushNum:  3,
+			wantNums: []int{3, 3, 5, 6, 7, 9, 11, 8},
+			comparator: func(src int, dst int) int {
+				return src - dst
+			},
+		},
 		{
 			name:     "MaxHeap",
 			nums:     []int{4, 1, 3, 2, 16, 9, 10, 14, 8, 7},
@@ -122,7 +139,7 @@ func TestHeap_Push(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			m := NewHeap[int](tc.nums, tc.comparator)
 			m.Push(tc.pushNum)
-			assert.Equal(t, tc.wantNums, m.data)
+			assert.Equal(t, tc.wantNums, values(m))
 		})
 	}
 }
@@ -189,7 +206,7 @@ func TestHeap_Pop(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			m := NewHeap[int](tc.nums, tc.comparator)
 			val, err := m.Pop()
-			assert.Equal(t, tc.wantNums, m.data)
+			assert.Equal(t, tc.wantNums, values(m))
 			assert.Equal(t, tc.wantVal, val)
 			assert.Equal(t, tc.wantErr, err)
 		})
@@ -259,3 +276,159 @@ func TestHeap_Peek(t *testing.T) {
 		})
 	}
 }
+
+func minComparator(src int, dst int) int {
+	if src < dst {
+		return -1
+	}
+	if src > dst {
+		return 1
+	}
+	return 0
+}
+
+// values 按照堆中的存储顺序返回所有元素
+func values[T any](h *Heap[T]) []T {
+	res := make([]T, 0, len(h.data))
+	for i, handle := range h.data {
+		if handle.index != i {
+			panic("handle index mismatch")
+		}
+		res = append(res, handle.val)
+	}
+	return res
+}
+
+// popAll 依次出堆，返回出堆的顺序
+func popAll[T any](h *Heap[T]) []T {
+	res := make([]T, 0, h.Size())
+	for h.Size() > 0 {
+		val, _ := h.Pop()
+		res = append(res, val)
+	}
+	return res
+}
+
+func TestHeap_Update(t *testing.T) {
+	testCases := []struct {
+		name     string
+		nums     []int
+		idx      int
+		newVal   int
+		invalid  bool
+		wantErr  error
+		wantNums []int
+	}{
+		{
+			name:     "decrease",
+			nums:     []int{8, 6, 11, 3, 7, 9, 5},
+			idx:      6,
+			newVal:   1,
+			wantNums: []int{1, 3, 6, 7, 8, 9, 11},
+		},
+		{
+			name:     "increase",
+			nums:     []int{8, 6, 11, 3, 7, 9, 5},
+			idx:      3,
+			newVal:   10,
+			wantNums: []int{5, 6, 7, 8, 9, 10, 11},
+		},
+		{
+			name:     "invalid handle",
+			nums:     []int{8, 6, 11, 3, 7, 9, 5},
+			invalid:  true,
+			wantErr:  ErrInvalidHandle,
+			wantNums: []int{3, 5, 6, 7, 8, 9, 11},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHeap[int](nil, minComparator)
+			handles := make([]*Handle[int], 0, len(tc.nums))
+			for _, num := range tc.nums {
+				handles = append(handles, h.Push(num))
+			}
+			handle := handles[tc.idx]
+			if tc.invalid {
+				handle = NewHeap[int](nil, minComparator).Push(1)
+			}
+			err := h.Update(handle, tc.newVal)
+			assert.Equal(t, tc.wantErr, err)
+			if err == nil {
+				assert.Equal(t, tc.newVal, handle.Value())
+			}
+			assert.Equal(t, tc.wantNums, popAll(h))
+		})
+	}
+}
+
+func TestHeap_Remove(t *testing.T) {
+	h := NewHeap[int](nil, minComparator)
+	handles := make([]*Handle[int], 0, 7)
+	for _, num := range []int{8, 6, 11, 3, 7, 9, 5} {
+		handles = append(handles, h.Push(num))
+	}
+	// 删除堆顶
+	val, err := h.Remove(handles[3])
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+	assert.Equal(t, -1, handles[3].Index())
+	// 删除中间的元素
+	val, err = h.Remove(handles[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 8, val)
+	// 重复删除
+	_, err = h.Remove(handles[0])
+	assert.Equal(t, ErrInvalidHandle, err)
+	_, err = h.Remove(nil)
+	assert.Equal(t, ErrInvalidHandle, err)
+
+	// 出堆之后的句柄也失效了
+	val, err = h.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, val)
+	assert.Equal(t, ErrInvalidHandle, h.Update(handles[6], 100))
+
+	assert.Equal(t, []int{6, 7, 9, 11}, popAll(h))
+}
+
+func TestHeap_Fix(t *testing.T) {
+	type item struct {
+		priority int
+	}
+	h := NewHeap[*item](nil, func(src *item, dst *item) int {
+		return src.priority - dst.priority
+	})
+	items := []*item{{5}, {3}, {8}, {1}}
+	for _, it := range items {
+		h.Push(it)
+	}
+	// 直接修改元素的内容，再调用 Fix
+	handle := h.Push(&item{priority: 10})
+	handle.Value().priority = 0
+	assert.NoError(t, h.Fix(handle.Index()))
+	top, err := h.Peek()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, top.priority)
+
+	assert.Equal(t, ErrIndexOutOfRange, h.Fix(-1))
+	assert.Equal(t, ErrIndexOutOfRange, h.Fix(h.Size()))
+}
+
+func TestHeap_Merge(t *testing.T) {
+	h1 := NewHeap[int]([]int{8, 6, 11}, minComparator)
+	h2 := NewHeap[int]([]int{3, 7}, minComparator)
+	handle := h2.Push(9)
+
+	h1.Merge(h2)
+	assert.Equal(t, 0, h2.Size())
+	assert.Equal(t, 6, h1.Size())
+	// 合并之后原来的句柄要通过新的堆操作
+	assert.Equal(t, ErrInvalidHandle, h2.Update(handle, 1))
+	assert.NoError(t, h1.Update(handle, 1))
+
+	// 合并自己或者 nil 什么都不做
+	h1.Merge(h1)
+	h1.Merge(nil)
+	assert.Equal(t, []int{1, 3, 6, 7, 8, 11}, popAll(h1))
+}
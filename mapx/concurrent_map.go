@@ -1,4 +1,333 @@
 package mapx
 
-type ConcurrentMap[T any] struct {
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+)
+
+const (
+	// 默认分片数，必须是 2 的幂
+	defaultShardCount = 32
+)
+
+// ConcurrentMap 并发安全的 map
+// 按照 key 的哈希值把数据分散到多个分片上，每个分片单独加锁，
+// 以此降低多个 goroutine 之间的锁竞争
+type ConcurrentMap[K comparable, V any] struct {
+	shards []*shard[K, V]
+	// 分片数减一，分片数是 2 的幂，用位运算代替取模
+	mask uint64
+	seed maphash.Seed
+	// hasher 调用方通过 WithHasher 指定的哈希函数
+	hasher func(key K) uint64
+}
+
+type shard[K comparable, V any] struct {
+	lock sync.RWMutex
+	data map[K]V
+	// 填充到一个缓存行的大小，避免相邻分片之间的伪共享
+	_ [32]byte
+}
+
+type options struct {
+	shardCount int
+	capacity   int
+	// hasher 是 func(key K) uint64，Option 不是泛型的，只能先存成 any
+	hasher any
+}
+
+type Option func(o *options)
+
+// WithShardCount 设置分片数，会向上取整为 2 的幂
+func WithShardCount(cnt int) Option {
+	return func(o *options) {
+		o.shardCount = cnt
+	}
+}
+
+// WithInitCapacity 设置 map 的初始容量，会平均分配到每一个分片上
+func WithInitCapacity(capacity int) Option {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+// WithHasher 指定 key 的哈希函数，K 必须和 NewConcurrentMap 的 K 一致，否则 NewConcurrentMap 会 panic
+// 相等的 key 必须返回相同的哈希值。
+// 默认的哈希函数对结构体、数组这类 key 要用反射逐个字段计算，
+// 热点路径上可以用这个选项换成更快的实现
+func WithHasher[K comparable](fn func(key K) uint64) Option {
+	return func(o *options) {
+		o.hasher = fn
+	}
+}
+
+func NewConcurrentMap[K comparable, V any](opts ...Option) *ConcurrentMap[K, V] {
+	o := &options{
+		shardCount: defaultShardCount,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cnt := roundUpPowerOfTwo(o.shardCount)
+	shardCap := 0
+	if o.capacity > 0 {
+		shardCap = (o.capacity + cnt - 1) / cnt
+	}
+	shards := make([]*shard[K, V], cnt)
+	for i := range shards {
+		shards[i] = &shard[K, V]{data: make(map[K]V, shardCap)}
+	}
+	m := &ConcurrentMap[K, V]{
+		shards: shards,
+		mask:   uint64(cnt - 1),
+		seed:   maphash.MakeSeed(),
+	}
+	if o.hasher != nil {
+		hasher, ok := o.hasher.(func(key K) uint64)
+		if !ok {
+			panic(fmt.Sprintf("mapx: WithHasher 的类型 %T 和 key 的类型不匹配", o.hasher))
+		}
+		m.hasher = hasher
+	}
+	return m
+}
+
+// Load 读取 key 对应的值，ok 表示 key 是否存在
+func (m *ConcurrentMap[K, V]) Load(key K) (V, bool) {
+	s := m.shard(key)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	val, ok := s.data[key]
+	return val, ok
+}
+
+func (m *ConcurrentMap[K, V]) Store(key K, val V) {
+	s := m.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data[key] = val
+}
+
+// LoadOrStore key 存在时返回已有的值，loaded 为 true；
+// 否则写入 val 并返回 val，loaded 为 false
+func (m *ConcurrentMap[K, V]) LoadOrStore(key K, val V) (actual V, loaded bool) {
+	s := m.shard(key)
+	// 大部分情况下 key 已经存在，先用读锁试一下
+	s.lock.RLock()
+	actual, loaded = s.data[key]
+	s.lock.RUnlock()
+	if loaded {
+		return actual, true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// double check，拿到写锁之前可能已经有别人写入了
+	actual, loaded = s.data[key]
+	if loaded {
+		return actual, true
+	}
+	s.data[key] = val
+	return val, false
+}
+
+// LoadAndDelete 删除 key 并返回被删除的值，loaded 表示 key 原本是否存在
+func (m *ConcurrentMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	s := m.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	val, loaded := s.data[key]
+	if loaded {
+		delete(s.data, key)
+	}
+	return val, loaded
+}
+
+func (m *ConcurrentMap[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.data, key)
+}
+
+// Compute 原子地计算 key 的新值
+// fn 的入参是旧值以及 key 是否存在，返回新值以及是否保留，
+// keep 为 false 时会删除 key。
+// 返回值是计算之后 key 对应的值以及 key 是否还存在。
+// 注意 fn 执行期间持有分片的写锁，不要在 fn 里面再操作这个 map
+func (m *ConcurrentMap[K, V]) Compute(key K,
+	fn func(oldVal V, loaded bool) (newVal V, keep bool)) (V, bool) {
+	s := m.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	oldVal, loaded := s.data[key]
+	newVal, keep := fn(oldVal, loaded)
+	if !keep {
+		delete(s.data, key)
+		var v V
+		return v, false
+	}
+	s.data[key] = newVal
+	return newVal, true
+}
+
+// Len 返回元素个数，并发修改的情况下只是一个近似值
+func (m *ConcurrentMap[K, V]) Len() int {
+	length := 0
+	for _, s := range m.shards {
+		s.lock.RLock()
+		length += len(s.data)
+		s.lock.RUnlock()
+	}
+	return length
+}
+
+// Range 遍历所有的键值对，fn 返回 false 时停止遍历
+// 遍历的是每个分片的快照，所以 fn 里面可以安全地修改这个 map，
+// 但是遍历期间的修改不一定能被观察到
+func (m *ConcurrentMap[K, V]) Range(fn func(key K, val V) bool) {
+	for _, s := range m.shards {
+		s.lock.RLock()
+		keys := make([]K, 0, len(s.data))
+		vals := make([]V, 0, len(s.data))
+		for k, v := range s.data {
+			keys = append(keys, k)
+			vals = append(vals, v)
+		}
+		s.lock.RUnlock()
+		for i := range keys {
+			if !fn(keys[i], vals[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (m *ConcurrentMap[K, V]) shard(key K) *shard[K, V] {
+	return m.shards[m.hash(key)&m.mask]
+}
+
+// hash 计算 key 的哈希值
+// 常见的类型走快速路径，其余类型用反射计算，
+// 指针、channel 按照地址计算，和 == 的语义保持一致
+func (m *ConcurrentMap[K, V]) hash(key K) uint64 {
+	if m.hasher != nil {
+		return m.hasher(key)
+	}
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(m.seed, k)
+	case int:
+		return mix(uint64(k))
+	case int8:
+		return mix(uint64(k))
+	case int16:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint8:
+		return mix(uint64(k))
+	case uint16:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uintptr:
+		return mix(uint64(k))
+	case float32:
+		return hashFloat(float64(k))
+	case float64:
+		return hashFloat(k)
+	default:
+		return m.hashValue(reflect.ValueOf(key))
+	}
+}
+
+// hashValue 按照 == 的语义递归计算哈希值，相等的值一定得到相同的结果
+func (m *ConcurrentMap[K, V]) hashValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		// nil 接口
+		return 0
+	case reflect.Bool:
+		if v.Bool() {
+			return mix(1)
+		}
+		return mix(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return mix(hashFloat(real(c)) ^ mix(hashFloat(imag(c))))
+	case reflect.String:
+		return maphash.String(m.seed, v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return mix(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return m.hashValue(v.Elem())
+	case reflect.Array:
+		var h uint64
+		for i := 0; i < v.Len(); i++ {
+			h = mix(h ^ m.hashValue(v.Index(i)))
+		}
+		return h
+	case reflect.Struct:
+		var h uint64
+		for i := 0; i < v.NumField(); i++ {
+			// == 会忽略 _ 字段
+			if v.Type().Field(i).Name == "_" {
+				continue
+			}
+			h = mix(h ^ m.hashValue(v.Field(i)))
+		}
+		return h
+	default:
+		// comparable 的类型不会走到这里
+		return 0
+	}
+}
+
+// hashFloat +0 和 -0 是相等的 key，要先统一成 +0
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix 打散整数的比特位，避免连续的整数都落在相邻的分片上
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// roundUpPowerOfTwo 向上取整为 2 的幂
+func roundUpPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	res := 1
+	for res < n {
+		res <<= 1
+	}
+	return res
 }
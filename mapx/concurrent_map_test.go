@@ -0,0 +1,345 @@
+package mapx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestNewConcurrentMap(t *testing.T) {
+	testCases := []struct {
+		name          string
+		opts          []Option
+		wantShardsCnt int
+	}{
+		{
+			name:          "default",
+			wantShardsCnt: defaultShardCount,
+		},
+		{
+			name:          "power of two",
+			opts:          []Option{WithShardCount(16)},
+			wantShardsCnt: 16,
+		},
+		{
+			name:          "round up",
+			opts:          []Option{WithShardCount(10), WithInitCapacity(100)},
+			wantShardsCnt: 16,
+		},
+		{
+			name:          "invalid shard count",
+			opts:          []Option{WithShardCount(-1)},
+			wantShardsCnt: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewConcurrentMap[string, int](tc.opts...)
+			assert.Equal(t, tc.wantShardsCnt, len(m.shards))
+			assert.Equal(t, uint64(tc.wantShardsCnt-1), m.mask)
+		})
+	}
+}
+
+func TestConcurrentMap_LoadStore(t *testing.T) {
+	m := NewConcurrentMap[string, int]()
+	_, ok := m.Load("a")
+	assert.False(t, ok)
+
+	m.Store("a", 1)
+	m.Store("b", 2)
+	m.Store("a", 3)
+	val, ok := m.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 3, val)
+	assert.Equal(t, 2, m.Len())
+
+	m.Delete("a")
+	_, ok = m.Load("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, m.Len())
+}
+
+func TestConcurrentMap_LoadOrStore(t *testing.T) {
+	m := NewConcurrentMap[int, string]()
+	actual, loaded := m.LoadOrStore(1, "a")
+	assert.False(t, loaded)
+	assert.Equal(t, "a", actual)
+
+	actual, loaded = m.LoadOrStore(1, "b")
+	assert.True(t, loaded)
+	assert.Equal(t, "a", actual)
+}
+
+func TestConcurrentMap_LoadAndDelete(t *testing.T) {
+	m := NewConcurrentMap[int, string]()
+	m.Store(1, "a")
+
+	val, loaded := m.LoadAndDelete(1)
+	assert.True(t, loaded)
+	assert.Equal(t, "a", val)
+
+	val, loaded = m.LoadAndDelete(1)
+	assert.False(t, loaded)
+	assert.Equal(t, "", val)
+}
+
+func TestConcurrentMap_Compute(t *testing.T) {
+	testCases := []struct {
+		name     string
+		before   func(m *ConcurrentMap[string, int])
+		fn       func(oldVal int, loaded bool) (int, bool)
+		wantVal  int
+		wantOk   bool
+		wantLoad bool
+	}{
+		{
+			name:   "key not exist",
+			before: func(m *ConcurrentMap[string, int]) {},
+			fn: func(oldVal int, loaded bool) (int, bool) {
+				return oldVal + 1, true
+			},
+			wantVal:  1,
+			wantOk:   true,
+			wantLoad: true,
+		},
+		{
+			name: "key exist",
+			before: func(m *ConcurrentMap[string, int]) {
+				m.Store("key", 10)
+			},
+			fn: func(oldVal int, loaded bool) (int, bool) {
+				return oldVal + 1, true
+			},
+			wantVal:  11,
+			wantOk:   true,
+			wantLoad: true,
+		},
+		{
+			name: "delete",
+			before: func(m *ConcurrentMap[string, int]) {
+				m.Store("key", 10)
+			},
+			fn: func(oldVal int, loaded bool) (int, bool) {
+				return 0, false
+			},
+			wantVal:  0,
+			wantOk:   false,
+			wantLoad: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewConcurrentMap[string, int]()
+			tc.before(m)
+			val, ok := m.Compute("key", tc.fn)
+			assert.Equal(t, tc.wantVal, val)
+			assert.Equal(t, tc.wantOk, ok)
+			_, loaded := m.Load("key")
+			assert.Equal(t, tc.wantLoad, loaded)
+		})
+	}
+}
+
+func TestConcurrentMap_Range(t *testing.T) {
+	m := NewConcurrentMap[int, int](WithShardCount(4))
+	for i := 0; i < 100; i++ {
+		m.Store(i, i*10)
+	}
+	res := make(map[int]int, 100)
+	m.Range(func(key int, val int) bool {
+		res[key] = val
+		// 遍历的时候修改 map 不会死锁
+		m.Delete(key)
+		return true
+	})
+	assert.Equal(t, 100, len(res))
+	for k, v := range res {
+		assert.Equal(t, k*10, v)
+	}
+	assert.Equal(t, 0, m.Len())
+
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+	cnt := 0
+	m.Range(func(key int, val int) bool {
+		cnt++
+		return cnt < 10
+	})
+	assert.Equal(t, 10, cnt)
+}
+
+func TestConcurrentMap_Hash(t *testing.T) {
+	type key struct {
+		a int
+		b string
+	}
+	m := NewConcurrentMap[key, int]()
+	m.Store(key{a: 1, b: "b"}, 1)
+	val, ok := m.Load(key{a: 1, b: "b"})
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+
+	fm := NewConcurrentMap[float64, int]()
+	negZero := -1.0 * 0
+	fm.Store(0, 1)
+	val, ok = fm.Load(negZero)
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+}
+
+func TestConcurrentMap_HashPointer(t *testing.T) {
+	type node struct {
+		val int
+	}
+	// 指针按照地址计算哈希值，修改指向的内容之后还能找到
+	m := NewConcurrentMap[*node, int](WithShardCount(64))
+	n := &node{val: 1}
+	m.Store(n, 1)
+	for i := 2; i < 100; i++ {
+		n.val = i
+		val, ok := m.Load(n)
+		assert.True(t, ok)
+		assert.Equal(t, 1, val)
+	}
+	_, ok := m.Load(&node{val: 99})
+	assert.False(t, ok)
+
+	cm := NewConcurrentMap[chan int, int]()
+	ch := make(chan int)
+	cm.Store(ch, 1)
+	val, ok := cm.Load(ch)
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+}
+
+func TestConcurrentMap_HashNegZero(t *testing.T) {
+	type key struct {
+		f float64
+		s string
+		i any
+	}
+	negZero := math.Copysign(0, -1)
+	m := NewConcurrentMap[key, int](WithShardCount(64))
+	m.Store(key{f: 0, s: "a", i: 0.0}, 1)
+	m.Store(key{f: negZero, s: "a", i: negZero}, 2)
+	assert.Equal(t, 1, m.Len())
+	val, ok := m.Load(key{f: 0, s: "a", i: 0.0})
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+}
+
+func TestConcurrentMap_WithHasher(t *testing.T) {
+	type key struct {
+		id   int
+		name string
+	}
+	cnt := 0
+	m := NewConcurrentMap[key, int](WithHasher(func(k key) uint64 {
+		cnt++
+		return uint64(k.id)
+	}))
+	m.Store(key{id: 1, name: "a"}, 1)
+	val, ok := m.Load(key{id: 1, name: "a"})
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, 2, cnt)
+
+	assert.Panics(t, func() {
+		NewConcurrentMap[string, int](WithHasher(func(k int) uint64 {
+			return uint64(k)
+		}))
+	})
+}
+
+func TestConcurrentMap_Concurrent(t *testing.T) {
+	m := NewConcurrentMap[int, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Compute(j, func(oldVal int, loaded bool) (int, bool) {
+					return oldVal + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1000, m.Len())
+	m.Range(func(key int, val int) bool {
+		assert.Equal(t, 10, val)
+		return true
+	})
+}
+
+// mutexMap 用一把读写锁保护的 map，作为 benchmark 的对照组
+type mutexMap[K comparable, V any] struct {
+	lock sync.RWMutex
+	data map[K]V
+}
+
+func (m *mutexMap[K, V]) Load(key K) (V, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	val, ok := m.data[key]
+	return val, ok
+}
+
+func (m *mutexMap[K, V]) Store(key K, val V) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.data[key] = val
+}
+
+const benchKeyCnt = 1024
+
+func benchKeys() []string {
+	keys := make([]string, benchKeyCnt)
+	for i := range keys {
+		keys[i] = "key_" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkConcurrentMap(b *testing.B) {
+	keys := benchKeys()
+	m := NewConcurrentMap[string, int]()
+	b.RunParallel(func(pb *testing.PB) {
+		// 每个 goroutine 从不同的位置开始，模拟访问不同的 key
+		i := rand.Intn(benchKeyCnt)
+		for pb.Next() {
+			key := keys[i%benchKeyCnt]
+			// 读写比例 3:1
+			if i%4 == 0 {
+				m.Store(key, i)
+			} else {
+				m.Load(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMutexMap(b *testing.B) {
+	keys := benchKeys()
+	m := &mutexMap[string, int]{data: make(map[string]int)}
+	b.RunParallel(func(pb *testing.PB) {
+		// 每个 goroutine 从不同的位置开始，模拟访问不同的 key
+		i := rand.Intn(benchKeyCnt)
+		for pb.Next() {
+			key := keys[i%benchKeyCnt]
+			if i%4 == 0 {
+				m.Store(key, i)
+			} else {
+				m.Load(key)
+			}
+			i++
+		}
+	})
+}
@@ -1,9 +1,10 @@
 package queue
 
 import (
+	"context"
 	"errors"
 	"github.com/Jared-lu/GXT"
-	"github.com/Jared-lu/GXT/internal/heap"
+	"github.com/Jared-lu/GXT/heap"
 	"sync"
 )
 
@@ -15,6 +16,10 @@ type ConcurrentPriorityQueue[T any] struct {
 	boundless  bool
 	comparator GXT.Comparator[T]
 	r          sync.RWMutex
+	// 队列非空的时候唤醒阻塞的出队者
+	notEmpty *cond
+	// 队列有空位的时候唤醒阻塞的入队者
+	notFull *cond
 }
 
 // NewConcurrentPriorityQueue
@@ -29,12 +34,15 @@ func NewConcurrentPriorityQueue[T any](capacity int, comparator GXT.Comparator[T
 	}
 	data := make([]T, 0, capacity)
 	h := heap.NewHeap[T](data, comparator)
-	return &ConcurrentPriorityQueue[T]{
+	q := &ConcurrentPriorityQueue[T]{
 		heap:       h,
 		capacity:   capacity,
 		boundless:  boundless,
 		comparator: comparator,
 	}
+	q.notEmpty = newCond(&q.r)
+	q.notFull = newCond(&q.r)
+	return q
 }
 
 func (p *ConcurrentPriorityQueue[T]) Enqueue(val T) error {
@@ -47,9 +55,29 @@ func (p *ConcurrentPriorityQueue[T]) Enqueue(val T) error {
 		} else {
 			return ErrOutOfCapacity
 		}
+		p.notEmpty.signal()
 		return nil
 	}
 	p.heap.Push(val)
+	p.notEmpty.signal()
+	return nil
+}
+
+// EnqueueCtx 阻塞入队，有界队列满了的时候会一直等到有空位
+func (p *ConcurrentPriorityQueue[T]) EnqueueCtx(ctx context.Context, val T) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	p.r.Lock()
+	defer p.r.Unlock()
+	for p.isFull() {
+		// 醒来之后要重新检查，空位可能已经被别的入队者抢走了
+		if err := p.notFull.wait(ctx); err != nil {
+			return err
+		}
+	}
+	p.heap.Push(val)
+	p.notEmpty.signal()
 	return nil
 }
 
@@ -60,9 +88,32 @@ func (p *ConcurrentPriorityQueue[T]) Dequeue() (T, error) {
 	if errors.Is(err, heap.ErrEmptyHeap) {
 		return val, ErrEmptyQueue
 	}
+	p.notFull.signal()
 	return val, err
 }
 
+// DequeueCtx 阻塞出队，队列为空的时候会一直等到有元素
+func (p *ConcurrentPriorityQueue[T]) DequeueCtx(ctx context.Context) (T, error) {
+	if ctx.Err() != nil {
+		var t T
+		return t, ctx.Err()
+	}
+	p.r.Lock()
+	defer p.r.Unlock()
+	for p.heap.Size() == 0 {
+		if err := p.notEmpty.wait(ctx); err != nil {
+			var t T
+			return t, err
+		}
+	}
+	val, err := p.heap.Pop()
+	if err != nil {
+		return val, err
+	}
+	p.notFull.signal()
+	return val, nil
+}
+
 func (p *ConcurrentPriorityQueue[T]) Peek() (T, error) {
 	p.r.RLock()
 	defer p.r.RUnlock()
@@ -97,4 +148,10 @@ func (p *ConcurrentPriorityQueue[T]) Clean() {
 	data := make([]T, 0, p.capacity)
 	h := heap.NewHeap[T](data, p.comparator)
 	p.heap = h
+	// 队列清空了，所有阻塞的入队者都有机会入队
+	p.notFull.broadcast()
+}
+
+func (p *ConcurrentPriorityQueue[T]) isFull() bool {
+	return !p.boundless && p.heap.Size() >= p.capacity
 }
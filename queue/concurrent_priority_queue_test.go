@@ -1,13 +1,204 @@
 package queue
 
 import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
+	"time"
 )
 
+func intComparator(src int, dst int) int {
+	if src < dst {
+		return -1
+	}
+	if src > dst {
+		return 1
+	}
+	return 0
+}
+
 func TestConcurrentPriorityQueue_Enqueue(t *testing.T) {
-	// TODO: 想一下怎么测
+	q := NewConcurrentPriorityQueue(3, intComparator)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	errCnt := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			if err := q.Enqueue(val); err != nil {
+				assert.Equal(t, ErrOutOfCapacity, err)
+				lock.Lock()
+				errCnt++
+				lock.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 3, q.Len())
+	assert.Equal(t, 7, errCnt)
 }
 
 func TestConcurrentPriorityQueue_Dequeue(t *testing.T) {
-	// TODO: 想一下怎么测
+	q := NewConcurrentPriorityQueue(0, intComparator)
+	for i := 9; i >= 0; i-- {
+		require.NoError(t, q.Enqueue(i))
+	}
+	for i := 0; i < 10; i++ {
+		val, err := q.Dequeue()
+		require.NoError(t, err)
+		assert.Equal(t, i, val)
+	}
+	_, err := q.Dequeue()
+	assert.Equal(t, ErrEmptyQueue, err)
+}
+
+func TestConcurrentPriorityQueue_EnqueueCtx(t *testing.T) {
+	testCases := []struct {
+		name    string
+		before  func(q *ConcurrentPriorityQueue[int])
+		timeout time.Duration
+		wantErr error
+		wantLen int
+	}{
+		{
+			name:    "not full",
+			before:  func(q *ConcurrentPriorityQueue[int]) {},
+			timeout: time.Second,
+			wantLen: 1,
+		},
+		{
+			name: "full and timeout",
+			before: func(q *ConcurrentPriorityQueue[int]) {
+				_ = q.Enqueue(1)
+				_ = q.Enqueue(2)
+			},
+			timeout: time.Millisecond * 100,
+			wantErr: context.DeadlineExceeded,
+			wantLen: 2,
+		},
+		{
+			name: "full and wait for dequeue",
+			before: func(q *ConcurrentPriorityQueue[int]) {
+				_ = q.Enqueue(1)
+				_ = q.Enqueue(2)
+				go func() {
+					time.Sleep(time.Millisecond * 100)
+					_, _ = q.Dequeue()
+				}()
+			},
+			timeout: time.Second,
+			wantLen: 2,
+		},
+		{
+			name: "full and wait for clean",
+			before: func(q *ConcurrentPriorityQueue[int]) {
+				_ = q.Enqueue(1)
+				_ = q.Enqueue(2)
+				go func() {
+					time.Sleep(time.Millisecond * 100)
+					q.Clean()
+				}()
+			},
+			timeout: time.Second,
+			wantLen: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := NewConcurrentPriorityQueue(2, intComparator)
+			tc.before(q)
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			err := q.EnqueueCtx(ctx, 10)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantLen, q.Len())
+		})
+	}
+}
+
+func TestConcurrentPriorityQueue_DequeueCtx(t *testing.T) {
+	testCases := []struct {
+		name    string
+		before  func(q *ConcurrentPriorityQueue[int])
+		timeout time.Duration
+		wantVal int
+		wantErr error
+	}{
+		{
+			name: "not empty",
+			before: func(q *ConcurrentPriorityQueue[int]) {
+				_ = q.Enqueue(2)
+				_ = q.Enqueue(1)
+			},
+			timeout: time.Second,
+			wantVal: 1,
+		},
+		{
+			name:    "empty and timeout",
+			before:  func(q *ConcurrentPriorityQueue[int]) {},
+			timeout: time.Millisecond * 100,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "empty and wait for enqueue",
+			before: func(q *ConcurrentPriorityQueue[int]) {
+				go func() {
+					time.Sleep(time.Millisecond * 100)
+					_ = q.EnqueueCtx(context.Background(), 3)
+				}()
+			},
+			timeout: time.Second,
+			wantVal: 3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := NewConcurrentPriorityQueue(2, intComparator)
+			tc.before(q)
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			val, err := q.DequeueCtx(ctx)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+func TestConcurrentPriorityQueue_ProducerConsumer(t *testing.T) {
+	q := NewConcurrentPriorityQueue(4, intComparator)
+	const producers, consumers, cnt = 8, 8, 500
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				require.NoError(t, q.EnqueueCtx(ctx, j))
+			}
+		}()
+	}
+	var lock sync.Mutex
+	total := 0
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < cnt; j++ {
+				_, err := q.DequeueCtx(ctx)
+				require.NoError(t, err)
+				lock.Lock()
+				total++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, producers*cnt, total)
+	assert.Equal(t, 0, q.Len())
 }
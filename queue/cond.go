@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// cond 基于 channel 实现的条件变量
+// 和 sync.Cond 的区别在于等待的时候可以通过 context 控制超时，
+// 并且 signal 只会按照等待的先后顺序唤醒一个等待者，避免惊群
+type cond struct {
+	L sync.Locker
+	// 每一个等待者都有一个自己的 channel
+	waiters *list.List
+}
+
+func newCond(l sync.Locker) *cond {
+	return &cond{L: l, waiters: list.New()}
+}
+
+// wait 调用之前必须持有 L，返回的时候也一定持有 L
+// 和 sync.Cond 一样，被唤醒之后调用者需要重新检查条件
+func (c *cond) wait(ctx context.Context) error {
+	ch := make(chan struct{}, 1)
+	elem := c.waiters.PushBack(ch)
+	c.L.Unlock()
+	select {
+	case <-ch:
+		c.L.Lock()
+		return nil
+	case <-ctx.Done():
+		c.L.Lock()
+		select {
+		case <-ch:
+			// 超时的同时也被唤醒了，这个信号要转交给下一个等待者，
+			// 否则这个信号就丢了
+			c.signal()
+		default:
+			c.waiters.Remove(elem)
+		}
+		return ctx.Err()
+	}
+}
+
+// signal 唤醒等待时间最长的一个等待者，调用之前必须持有 L
+func (c *cond) signal() {
+	elem := c.waiters.Front()
+	if elem == nil {
+		return
+	}
+	c.waiters.Remove(elem)
+	elem.Value.(chan struct{}) <- struct{}{}
+}
+
+// broadcast 唤醒所有的等待者，调用之前必须持有 L
+func (c *cond) broadcast() {
+	for c.waiters.Len() > 0 {
+		c.signal()
+	}
+}
@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/heap"
+	"sync"
+	"time"
+)
+
+// Delayable 延时队列里面的元素
+type Delayable interface {
+	// Deadline 元素到期的时间，到期之后才能出队
+	Deadline() time.Time
+}
+
+// DelayQueue 延时队列，元素按照到期时间排序，只有到期的元素才能出队
+// 当 capacity <= 0 时，为无界队列
+// 当 capacity > 0 时，为有界队列
+type DelayQueue[T Delayable] struct {
+	heap     *heap.Heap[T]
+	capacity int
+	// 有界/无界
+	boundless bool
+	lock      sync.Mutex
+	// 队头发生了变化，唤醒等待的出队者重新计算等待时间
+	headChanged *cond
+	// 队列有空位的时候唤醒阻塞的入队者
+	notFull *cond
+}
+
+func NewDelayQueue[T Delayable](capacity int) *DelayQueue[T] {
+	var boundless bool
+	if capacity <= 0 {
+		// 无界队列初始化容量为64
+		capacity = 64
+		boundless = true
+	}
+	data := make([]T, 0, capacity)
+	h := heap.NewHeap[T](data, func(src T, dst T) int {
+		return src.Deadline().Compare(dst.Deadline())
+	})
+	q := &DelayQueue[T]{
+		heap:      h,
+		capacity:  capacity,
+		boundless: boundless,
+	}
+	q.headChanged = newCond(&q.lock)
+	q.notFull = newCond(&q.lock)
+	return q
+}
+
+// Enqueue 入队，有界队列满了的时候会一直等到有空位
+func (q *DelayQueue[T]) Enqueue(ctx context.Context, val T) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for !q.boundless && q.heap.Size() >= q.capacity {
+		if err := q.notFull.wait(ctx); err != nil {
+			return err
+		}
+	}
+	q.heap.Push(val)
+	head, _ := q.heap.Peek()
+	if head.Deadline().Equal(val.Deadline()) {
+		// 新元素成为了队头，等待中的出队者都要醒来重新计算等待时间，
+		// 否则它们会按照原来队头的到期时间继续睡下去
+		q.headChanged.broadcast()
+	}
+	return nil
+}
+
+// Dequeue 出队，会一直阻塞直到队头的元素到期
+func (q *DelayQueue[T]) Dequeue(ctx context.Context) (T, error) {
+	var t T
+	if ctx.Err() != nil {
+		return t, ctx.Err()
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for {
+		head, err := q.heap.Peek()
+		if err != nil {
+			// 队列为空，等有元素入队
+			if err = q.headChanged.wait(ctx); err != nil {
+				return t, err
+			}
+			continue
+		}
+		deadline := head.Deadline()
+		if !time.Now().Before(deadline) {
+			// 队头到期了
+			val, _ := q.heap.Pop()
+			q.notFull.signal()
+			return val, nil
+		}
+		// 等到队头到期，或者有更早到期的元素入队
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		_ = q.headChanged.wait(waitCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return t, ctx.Err()
+		}
+	}
+}
+
+func (q *DelayQueue[T]) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.heap.Size()
+}
@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type delayElem struct {
+	deadline time.Time
+	val      int
+}
+
+func (d delayElem) Deadline() time.Time {
+	return d.deadline
+}
+
+func TestDelayQueue_Dequeue(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name    string
+		before  func(q *DelayQueue[delayElem])
+		timeout time.Duration
+		wantVal int
+		wantErr error
+	}{
+		{
+			name:    "empty and timeout",
+			before:  func(q *DelayQueue[delayElem]) {},
+			timeout: time.Millisecond * 100,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "already expired",
+			before: func(q *DelayQueue[delayElem]) {
+				_ = q.Enqueue(context.Background(), delayElem{deadline: now.Add(time.Second), val: 2})
+				_ = q.Enqueue(context.Background(), delayElem{deadline: now.Add(-time.Second), val: 1})
+			},
+			timeout: time.Second,
+			wantVal: 1,
+		},
+		{
+			name: "not expired and timeout",
+			before: func(q *DelayQueue[delayElem]) {
+				_ = q.Enqueue(context.Background(), delayElem{deadline: now.Add(time.Minute), val: 1})
+			},
+			timeout: time.Millisecond * 100,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "wait until expired",
+			before: func(q *DelayQueue[delayElem]) {
+				_ = q.Enqueue(context.Background(), delayElem{deadline: now.Add(time.Millisecond * 100), val: 1})
+			},
+			timeout: time.Second,
+			wantVal: 1,
+		},
+		{
+			name: "empty and wait for enqueue",
+			before: func(q *DelayQueue[delayElem]) {
+				go func() {
+					time.Sleep(time.Millisecond * 100)
+					_ = q.Enqueue(context.Background(), delayElem{deadline: time.Now(), val: 1})
+				}()
+			},
+			timeout: time.Second,
+			wantVal: 1,
+		},
+		{
+			name: "earlier element enqueued",
+			before: func(q *DelayQueue[delayElem]) {
+				_ = q.Enqueue(context.Background(), delayElem{deadline: now.Add(time.Minute), val: 1})
+				go func() {
+					time.Sleep(time.Millisecond * 100)
+					_ = q.Enqueue(context.Background(), delayElem{deadline: time.Now().Add(time.Millisecond * 100), val: 2})
+				}()
+			},
+			timeout: time.Second,
+			wantVal: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := NewDelayQueue[delayElem](0)
+			tc.before(q)
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			val, err := q.Dequeue(ctx)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val.val)
+			assert.False(t, time.Now().Before(val.deadline))
+		})
+	}
+}
+
+func TestDelayQueue_Enqueue(t *testing.T) {
+	q := NewDelayQueue[delayElem](1)
+	require.NoError(t, q.Enqueue(context.Background(), delayElem{deadline: time.Now(), val: 1}))
+
+	// 队列满了
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	err := q.Enqueue(ctx, delayElem{deadline: time.Now(), val: 2})
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// 出队之后就有空位了
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		_, _ = q.Dequeue(context.Background())
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	err = q.Enqueue(ctx, delayElem{deadline: time.Now(), val: 2})
+	cancel()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestDelayQueue_Order(t *testing.T) {
+	q := NewDelayQueue[delayElem](0)
+	now := time.Now()
+	for _, i := range []int{3, 1, 4, 2, 5} {
+		require.NoError(t, q.Enqueue(context.Background(),
+			delayElem{deadline: now.Add(time.Millisecond * time.Duration(i*20)), val: i}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 1; i <= 5; i++ {
+		val, err := q.Dequeue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, i, val.val)
+	}
+}
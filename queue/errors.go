@@ -4,3 +4,4 @@ import "errors"
 
 var ErrEmptyQueue = errors.New("queue is empty")
 var ErrOutOfCapacity = errors.New("queue is out of capacity")
+var ErrInvalidHandle = errors.New("invalid queue handle")
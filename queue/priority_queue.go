@@ -3,7 +3,7 @@ package queue
 import (
 	"errors"
 	"github.com/Jared-lu/GXT"
-	"github.com/Jared-lu/GXT/internal/heap"
+	"github.com/Jared-lu/GXT/heap"
 )
 
 // PriorityQueue 优先级队列，支持有界与无界
@@ -36,17 +36,17 @@ func NewPriorityQueue[T any](capacity int, comparator GXT.Comparator[T]) *Priori
 }
 
 func (p *PriorityQueue[T]) Enqueue(val T) error {
-	if !p.boundless {
-		// 有界队列，在入队前要先查看是否还有空位
-		if p.capacity > p.heap.Size() {
-			p.heap.Push(val)
-		} else {
-			return ErrOutOfCapacity
-		}
-		return nil
+	_, err := p.EnqueueWithHandle(val)
+	return err
+}
+
+// EnqueueWithHandle 入队并返回元素的句柄，之后可以通过句柄修改元素的优先级或者删除元素
+func (p *PriorityQueue[T]) EnqueueWithHandle(val T) (*heap.Handle[T], error) {
+	// 有界队列，在入队前要先查看是否还有空位
+	if !p.boundless && p.capacity <= p.heap.Size() {
+		return nil, ErrOutOfCapacity
 	}
-	p.heap.Push(val)
-	return nil
+	return p.heap.Push(val), nil
 }
 
 func (p *PriorityQueue[T]) Dequeue() (T, error) {
@@ -65,6 +65,24 @@ func (p *PriorityQueue[T]) Peek() (T, error) {
 	return val, err
 }
 
+// Remove 删除句柄对应的元素，元素已经出队或者被删除时返回 ErrInvalidHandle
+func (p *PriorityQueue[T]) Remove(handle *heap.Handle[T]) (T, error) {
+	val, err := p.heap.Remove(handle)
+	if errors.Is(err, heap.ErrInvalidHandle) {
+		return val, ErrInvalidHandle
+	}
+	return val, err
+}
+
+// Update 修改句柄对应元素的值，并调整它的优先级
+func (p *PriorityQueue[T]) Update(handle *heap.Handle[T], newVal T) error {
+	err := p.heap.Update(handle, newVal)
+	if errors.Is(err, heap.ErrInvalidHandle) {
+		return ErrInvalidHandle
+	}
+	return err
+}
+
 func (p *PriorityQueue[T]) Len() int {
 	return p.heap.Size()
 }
@@ -280,3 +280,46 @@ func TestPriorityQueue_Clean(t *testing.T) {
 		})
 	}
 }
+
+func TestPriorityQueue_Remove(t *testing.T) {
+	q := NewPriorityQueue(3, intComparator)
+	h1, err := q.EnqueueWithHandle(1)
+	assert.NoError(t, err)
+	h2, err := q.EnqueueWithHandle(2)
+	assert.NoError(t, err)
+	_, err = q.EnqueueWithHandle(3)
+	assert.NoError(t, err)
+	_, err = q.EnqueueWithHandle(4)
+	assert.Equal(t, ErrOutOfCapacity, err)
+
+	val, err := q.Remove(h1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, 2, q.Len())
+	_, err = q.Remove(h1)
+	assert.Equal(t, ErrInvalidHandle, err)
+
+	// 出队之后句柄就失效了
+	val, err = q.Dequeue()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	_, err = q.Remove(h2)
+	assert.Equal(t, ErrInvalidHandle, err)
+}
+
+func TestPriorityQueue_Update(t *testing.T) {
+	q := NewPriorityQueue(0, intComparator)
+	_ = q.Enqueue(1)
+	h, err := q.EnqueueWithHandle(5)
+	assert.NoError(t, err)
+	_ = q.Enqueue(3)
+
+	assert.NoError(t, q.Update(h, 0))
+	val, err := q.Peek()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, val)
+
+	// 清空之后句柄就失效了
+	q.Clean()
+	assert.Equal(t, ErrInvalidHandle, q.Update(h, 10))
+}
@@ -1,6 +1,16 @@
 package queue
 
+import "context"
+
 type Queue[T any] interface {
 	Enqueue(val T) error
 	Dequeue() (T, error)
 }
+
+// BlockingQueue 阻塞队列
+// 队列满了的时候，入队会阻塞直到有空位；队列为空的时候，出队会阻塞直到有元素。
+// 阻塞期间 ctx 过期或者被取消，会返回 ctx.Err()
+type BlockingQueue[T any] interface {
+	EnqueueCtx(ctx context.Context, val T) error
+	DequeueCtx(ctx context.Context) (T, error)
+}
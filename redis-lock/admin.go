@@ -0,0 +1,217 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed lua/inspect.lua
+var luaInspectSrc string
+var luaInspect = redis.NewScript(luaInspectSrc)
+
+//go:embed lua/force_release.lua
+var luaForceReleaseSrc string
+var luaForceRelease = redis.NewScript(luaForceReleaseSrc)
+
+// ErrLockNotFound 锁不存在，或者不是 Lock、TryLock 加的锁，比如没有持有者信息的 key
+var ErrLockNotFound = errors.New("lock not found")
+
+// 持有者信息在 hash 里面的字段
+const (
+	ownerFieldHostname   = "hostname"
+	ownerFieldPID        = "pid"
+	ownerFieldAcquiredAt = "acquired_at"
+	ownerFieldLabel      = "label"
+)
+
+// scanCount 每次 SCAN 的 COUNT
+const scanCount = 100
+
+// Owner 锁的持有者信息，加锁的时候写入，和锁一起过期
+type Owner struct {
+	Hostname string
+	PID      int
+	// AcquiredAt 加锁的时间，以加锁的机器的时钟为准
+	AcquiredAt time.Time
+	// Label 见 WithOwnerLabel
+	Label string
+}
+
+// LockInfo 锁当前的状态
+type LockInfo struct {
+	Key string
+	// Value 持有者的标识
+	Value string
+	// TTL 剩余的过期时间，-1 代表没有设置过期时间
+	TTL time.Duration
+	// FencingToken 这把锁最近一次发出的 fencing token
+	FencingToken int64
+	// Owner 持有者信息
+	Owner *Owner
+}
+
+// Inspect 查看一把锁，锁不存在的时候返回 ErrLockNotFound
+// 只支持 Lock、TryLock 以及公平锁加的锁，也就是有持有者信息的 key，
+// 其它类型的锁以及业务自己的 key 也返回 ErrLockNotFound
+func (c *Client) Inspect(ctx context.Context, key string) (LockInfo, error) {
+	return runLockInfo(ctx, c.client, luaInspect, key)
+}
+
+// ForceRelease 不管持有者是谁，强行释放一把锁，返回释放之前的状态
+// 和 Inspect 一样只支持 Lock、TryLock 以及公平锁加的锁，其它的 key 不会被删除，返回 ErrLockNotFound。
+// 用于持有者已经卡死、又不想等锁过期的场景。
+// 原来的持有者不会立刻知道，看门狗下一次续约的时候才会发现锁丢了，
+// 所以在它发现之前，可能会有两个人同时认为自己持有锁，业务要用 fencing token 兜底
+func (c *Client) ForceRelease(ctx context.Context, key string) (LockInfo, error) {
+	return runLockInfo(ctx, c.client, luaForceRelease, key, c.publishFlag())
+}
+
+// ListLocks 用 SCAN 列出所有以 prefix 开头的锁，按照 key 排序
+// redis.ClusterClient 会扫描所有的主节点，redis.Ring 会扫描所有的分片。
+// 扫描期间加的锁或者释放的锁可能出现也可能不出现
+func (c *Client) ListLocks(ctx context.Context, prefix string) ([]LockInfo, error) {
+	keys, err := c.scan(ctx, escapePattern(prefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	res := make([]LockInfo, 0, len(keys))
+	for _, key := range keys {
+		if isDerivedKey(key) {
+			continue
+		}
+		info, err := c.Inspect(ctx, key)
+		if errors.Is(err, ErrLockNotFound) {
+			// 已经释放了，或者不是锁
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, info)
+	}
+	return res, nil
+}
+
+// isDerivedKey key 是不是 ownerKey、fencingKey 生成的 key
+// 只看后缀的话会把 job:owner 之类的业务自己的锁也过滤掉，所以要和 sameSlotKey 生成的形式完全一致
+func isDerivedKey(key string) bool {
+	for _, suffix := range []string{":owner", ":fencing"} {
+		base, ok := strings.CutSuffix(key, suffix)
+		if !ok {
+			continue
+		}
+		// base 自己带了 hash tag，派生的 key 是 base + suffix
+		if sameSlotKey(base, suffix) == key {
+			return true
+		}
+		// 派生的 key 是 {base}suffix
+		if len(base) > 2 && base[0] == '{' && base[len(base)-1] == '}' &&
+			sameSlotKey(base[1:len(base)-1], suffix) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) scan(ctx context.Context, match string) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, match, scanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+	var err error
+	switch client := c.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	case *redis.Ring:
+		err = client.ForEachShard(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	default:
+		err = scan(ctx, c.client)
+	}
+	return keys, err
+}
+
+// escapePattern 转义 SCAN MATCH 里面的特殊字符
+func escapePattern(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func runLockInfo(ctx context.Context, client redis.Scripter, script *redis.Script,
+	key string, args ...any) (LockInfo, error) {
+	res, err := script.Run(ctx, client, []string{key, fencingKey(key), ownerKey(key)}, args...).Slice()
+	if errors.Is(err, redis.Nil) {
+		return LockInfo{}, ErrLockNotFound
+	}
+	if err != nil {
+		return LockInfo{}, err
+	}
+	return parseLockInfo(key, res)
+}
+
+// parseLockInfo 解析 lua/inspect.lua 的返回值
+func parseLockInfo(key string, res []any) (LockInfo, error) {
+	if len(res) != 4 {
+		return LockInfo{}, fmt.Errorf("redis-lock: 非法的锁信息 %v", res)
+	}
+	value, _ := res[0].(string)
+	pttl, _ := res[1].(int64)
+	token, _ := res[2].(int64)
+	info := LockInfo{
+		Key:          key,
+		Value:        value,
+		TTL:          time.Duration(pttl) * time.Millisecond,
+		FencingToken: token,
+	}
+	if pttl < 0 {
+		info.TTL = -1
+	}
+	fields, _ := res[3].([]any)
+	if len(fields) == 0 {
+		return info, nil
+	}
+	owner := &Owner{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, _ := fields[i].(string)
+		val, _ := fields[i+1].(string)
+		switch field {
+		case ownerFieldHostname:
+			owner.Hostname = val
+		case ownerFieldPID:
+			owner.PID, _ = strconv.Atoi(val)
+		case ownerFieldAcquiredAt:
+			ms, _ := strconv.ParseInt(val, 10, 64)
+			owner.AcquiredAt = time.UnixMilli(ms)
+		case ownerFieldLabel:
+			owner.Label = val
+		}
+	}
+	info.Owner = owner
+	return info, nil
+}
@@ -0,0 +1,78 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_e2e_Admin(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	client := NewClient(rdb, WithOwnerLabel("e2e"), WithoutWatchdog())
+
+	before := time.Now().Add(-time.Second)
+	l1, err := client.TryLock(ctx, "admin:key1", time.Minute)
+	require.NoError(t, err)
+	defer l1.Unlock(ctx)
+	l2, err := client.TryLock(ctx, "admin:key2", time.Minute)
+	require.NoError(t, err)
+	// 其它类型的锁以及业务自己的 key 不会出现在结果里面
+	require.NoError(t, rdb.HSet(ctx, "admin:rwlock", "mode", "read").Err())
+	defer rdb.Del(ctx, "admin:rwlock")
+	require.NoError(t, rdb.Set(ctx, "admin:config", "value", 0).Err())
+	defer rdb.Del(ctx, "admin:config")
+
+	info, err := client.Inspect(ctx, "admin:key1")
+	require.NoError(t, err)
+	assert.Equal(t, l1.value, info.Value)
+	assert.Equal(t, l1.FencingToken(), info.FencingToken)
+	assert.True(t, info.TTL > 0 && info.TTL <= time.Minute)
+	require.NotNil(t, info.Owner)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, info.Owner.Hostname)
+	assert.Equal(t, os.Getpid(), info.Owner.PID)
+	assert.Equal(t, "e2e", info.Owner.Label)
+	assert.True(t, info.Owner.AcquiredAt.After(before))
+
+	locks, err := client.ListLocks(ctx, "admin:")
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.Equal(t, "admin:key1", locks[0].Key)
+	assert.Equal(t, "admin:key2", locks[1].Key)
+
+	// 不是锁的 key 不能被强行释放
+	_, err = client.ForceRelease(ctx, "admin:config")
+	assert.Equal(t, ErrLockNotFound, err)
+	val, err := rdb.Get(ctx, "admin:config").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	// 强行释放之后原来的持有者不能再续约
+	info, err = client.ForceRelease(ctx, "admin:key2")
+	require.NoError(t, err)
+	assert.Equal(t, l2.value, info.Value)
+	assert.Equal(t, ErrLockNotHeld, l2.Refresh(ctx))
+	_, err = client.Inspect(ctx, "admin:key2")
+	assert.Equal(t, ErrLockNotFound, err)
+	_, err = client.ForceRelease(ctx, "admin:key2")
+	assert.Equal(t, ErrLockNotFound, err)
+	exists, err := rdb.Exists(ctx, ownerKey("admin:key2")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+
+	// fencing token 依旧递增
+	l3, err := client.TryLock(ctx, "admin:key2", time.Minute)
+	require.NoError(t, err)
+	defer l3.Unlock(ctx)
+	assert.Greater(t, l3.FencingToken(), l2.FencingToken())
+}
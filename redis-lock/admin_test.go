@@ -0,0 +1,159 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_Inspect(t *testing.T) {
+	acquiredAt := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantInfo LockInfo
+		wantErr  error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+					[]string{"key1", "{key1}:fencing", "{key1}:owner"}).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not found",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(redis.Nil)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+					[]string{"key1", "{key1}:fencing", "{key1}:owner"}).Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotFound,
+		},
+		{
+			name: "without owner",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal([]any{"value1", int64(-1), int64(0), []any{}})
+				cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+					[]string{"key1", "{key1}:fencing", "{key1}:owner"}).Return(res)
+				return cmd
+			},
+			wantInfo: LockInfo{Key: "key1", Value: "value1", TTL: -1},
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal([]any{"value1", int64(1500), int64(3), []any{
+					"hostname", "host1", "pid", "42", "acquired_at", "1700000000000", "label", "job",
+				}})
+				cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+					[]string{"key1", "{key1}:fencing", "{key1}:owner"}).Return(res)
+				return cmd
+			},
+			wantInfo: LockInfo{
+				Key:          "key1",
+				Value:        "value1",
+				TTL:          time.Millisecond * 1500,
+				FencingToken: 3,
+				Owner: &Owner{
+					Hostname:   "host1",
+					PID:        42,
+					AcquiredAt: acquiredAt,
+					Label:      "job",
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			info, err := client.Inspect(context.Background(), "key1")
+			assert.True(t, errors.Is(err, tc.wantErr))
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantInfo, info)
+		})
+	}
+}
+
+func TestClient_ListLocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	scanRes := redis.NewScanCmd(context.Background(), nil)
+	scanRes.SetVal([]string{"job:2", "{job:2}:fencing", "{job:2}:owner", "job:1", "job:3",
+		"job:owner", "{job:owner}:owner"}, 0)
+	cmd.EXPECT().Scan(gomock.Any(), uint64(0), "job:*", int64(scanCount)).Return(scanRes)
+	info := func(val string) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal([]any{val, int64(1000), int64(1), []any{}})
+		return res
+	}
+	notFound := redis.NewCmd(context.Background())
+	notFound.SetErr(redis.Nil)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+		[]string{"job:1", "{job:1}:fencing", "{job:1}:owner"}).Return(info("value1"))
+	// 扫描之后释放了
+	cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+		[]string{"job:2", "{job:2}:fencing", "{job:2}:owner"}).Return(notFound)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+		[]string{"job:3", "{job:3}:fencing", "{job:3}:owner"}).Return(info("value3"))
+	// 业务自己的锁刚好以 :owner 结尾，不能被过滤掉
+	cmd.EXPECT().EvalSha(gomock.Any(), luaInspect.Hash(),
+		[]string{"job:owner", "{job:owner}:fencing", "{job:owner}:owner"}).Return(info("value4"))
+
+	client := NewClient(cmd)
+	locks, err := client.ListLocks(context.Background(), "job:")
+	assert.NoError(t, err)
+	assert.Equal(t, []LockInfo{
+		{Key: "job:1", Value: "value1", TTL: time.Second, FencingToken: 1},
+		{Key: "job:3", Value: "value3", TTL: time.Second, FencingToken: 1},
+		{Key: "job:owner", Value: "value4", TTL: time.Second, FencingToken: 1},
+	}, locks)
+}
+
+func TestIsDerivedKey(t *testing.T) {
+	testCases := []struct {
+		key  string
+		want bool
+	}{
+		{key: "{job}:owner", want: true},
+		{key: "{job}:fencing", want: true},
+		{key: "{user}:job:owner", want: true},
+		{key: "job:owner", want: false},
+		{key: "job:fencing", want: false},
+		{key: "{job:owner}", want: false},
+		{key: "job", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			assert.Equal(t, tc.want, isDerivedKey(tc.key))
+		})
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	assert.Equal(t, "job:", escapePattern("job:"))
+	assert.Equal(t, `a\*b\?\[c\]\\`, escapePattern(`a*b?[c]\`))
+}
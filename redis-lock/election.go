@@ -0,0 +1,211 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoLeader 当前没有 leader
+var ErrNoLeader = errors.New("no leader")
+
+// leaderValueSep 锁的 value 是 <identity>#<uuid>，
+// 加上 uuid 是为了防止两个副本配置了同样的 identity 的时候都认为自己是 leader
+const leaderValueSep = "#"
+
+// LeaderCallbacks 成为 leader 以及不再是 leader 的回调
+type LeaderCallbacks struct {
+	// OnStartedLeading 成为 leader 之后在一个新的 goroutine 里面调用，
+	// 不再是 leader 的时候 ctx 会被取消，业务要立刻停下来
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading 主动放弃或者丢失 leader 身份之后调用，每一次当选只会调用一次
+	OnStoppedLeading func()
+}
+
+// Election 基于分布式锁的选主，谁拿到锁谁就是 leader
+// 当选之后看门狗会一直续约，续约失败或者锁被别人拿走都会立刻退位
+type Election struct {
+	client     *Client
+	key        string
+	identity   string
+	expiration time.Duration
+	// 竞选失败之后多久再试一次
+	interval  time.Duration
+	callbacks LeaderCallbacks
+
+	mu   sync.Mutex
+	lock *Lock
+	// 当前这一任结束，并且 OnStoppedLeading 执行完之后关闭
+	stopped chan struct{}
+}
+
+type ElectionOption func(e *Election)
+
+// WithCampaignInterval 竞选失败之后重试的间隔，默认是过期时间的三分之一
+// 开启了 WithNotification 的时候，leader 退位之后会被立刻唤醒
+func WithCampaignInterval(interval time.Duration) ElectionOption {
+	return func(e *Election) {
+		e.interval = interval
+	}
+}
+
+// NewElection 创建选主组件
+// identity 是这个副本的标识，比如主机名，Leader 返回的就是它
+func (c *Client) NewElection(key string, identity string, expiration time.Duration,
+	callbacks LeaderCallbacks, opts ...ElectionOption) *Election {
+	e := &Election{
+		client:     c,
+		key:        key,
+		identity:   identity,
+		expiration: expiration,
+		interval:   expiration / 3,
+		callbacks:  callbacks,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Campaign 竞选，阻塞直到当选或者 ctx 过期
+// 已经是 leader 的时候直接返回。
+// 退位之后不会自动重新竞选，需要再次调用 Campaign，
+// 上一任的 OnStoppedLeading 执行完之前不会开始竞选，保证两任之间不会重叠
+func (e *Election) Campaign(ctx context.Context) error {
+	if e.IsLeader() {
+		return nil
+	}
+	e.mu.Lock()
+	prev := e.stopped
+	e.mu.Unlock()
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	val := e.identity + leaderValueSep + uuid.New().String()
+	var wake <-chan struct{}
+	if e.client.notifier != nil {
+		var cancel func()
+		wake, cancel = e.client.notifier.wait(ctx, e.key)
+		defer cancel()
+	}
+	c := e.client
+	begin := time.Now()
+	attempts := 0
+	var token int64
+	var start time.Time
+	// 竞选会一直重试，直到 ctx 过期
+	err := retryLock(ctx, e.expiration, retry.FixedInterval(e.interval, 0)(),
+		wake, func(ctx context.Context) (bool, error) {
+			var err error
+			attempts++
+			start = time.Now()
+			token, err = c.attempt(ctx, e.key, attempts, func(ctx context.Context) (int64, error) {
+				return c.lock(ctx, e.key, val, e.expiration, c.owner)
+			})
+			if err != nil && isTransientErr(err) {
+				// 网络抖动之类的错误，继续竞选，ctx 过期的时候 retryLock 会返回
+				return false, nil
+			}
+			return token > 0, err
+		})
+	c.observeAcquire(ctx, e.key, attempts, begin, err)
+	if err != nil {
+		return err
+	}
+	l := c.newLock(e.key, val, e.expiration, token, start, nil)
+	// 关闭了看门狗也要续约，不然 leader 会在过期之后不知不觉地丢掉身份
+	l.startWatchdog(0, 0)
+
+	stopped := make(chan struct{})
+	e.mu.Lock()
+	e.lock = l
+	e.stopped = stopped
+	e.mu.Unlock()
+
+	if e.callbacks.OnStartedLeading != nil {
+		go e.callbacks.OnStartedLeading(l.Context())
+	}
+	go e.watch(l, stopped)
+	return nil
+}
+
+// watch 等待这一任结束
+func (e *Election) watch(l *Lock, stopped chan struct{}) {
+	<-l.Context().Done()
+	if e.callbacks.OnStoppedLeading != nil {
+		e.callbacks.OnStoppedLeading()
+	}
+	// 回调执行完才算这一任结束
+	e.mu.Lock()
+	if e.lock == l {
+		e.lock = nil
+	}
+	e.mu.Unlock()
+	close(stopped)
+}
+
+// Resign 主动退位，会等到 OnStoppedLeading 执行完再返回
+// 不是 leader 的时候直接返回
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	l, stopped := e.lock, e.stopped
+	e.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	err := l.Unlock(ctx)
+	if err != nil && !errors.Is(err, ErrLockNotHeld) {
+		// 释放锁失败，锁会在过期之后自动释放，但是本地已经退位了
+		return err
+	}
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsLeader 自己当前是不是 leader
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lock != nil && e.lock.Context().Err() == nil
+}
+
+// Leader 当前 leader 的 identity，没有 leader 的时候返回 ErrNoLeader
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	val, err := e.client.client.Get(ctx, e.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNoLeader
+	}
+	if err != nil {
+		return "", err
+	}
+	if idx := strings.LastIndex(val, leaderValueSep); idx >= 0 {
+		return val[:idx], nil
+	}
+	return val, nil
+}
+
+// isTransientErr 网络错误以及单次请求超时，重试可能会成功
+// WRONGTYPE、NOPERM、脚本错误之类的重试多少次都一样，要返回给调用者
+func isTransientErr(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
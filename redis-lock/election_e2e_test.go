@@ -0,0 +1,61 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_Election(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithNotification(rdb))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	key := "election_key"
+	defer rdb.Del(ctx, key, fencingKey(key))
+
+	stopped1 := make(chan struct{})
+	e1 := client.NewElection(key, "node1", time.Second*3, LeaderCallbacks{
+		OnStoppedLeading: func() {
+			close(stopped1)
+		},
+	})
+	started2 := make(chan struct{})
+	e2 := client.NewElection(key, "node2", time.Second*3, LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			close(started2)
+		},
+	})
+
+	require.NoError(t, e1.Campaign(ctx))
+	leader, err := e2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "node1", leader)
+
+	campaignErr := make(chan error, 1)
+	go func() {
+		campaignErr <- e2.Campaign(ctx)
+	}()
+	time.Sleep(time.Millisecond * 200)
+	assert.False(t, e2.IsLeader())
+
+	// node1 退位之后 node2 会被通知，立刻当选
+	require.NoError(t, e1.Resign(ctx))
+	<-stopped1
+	require.NoError(t, <-campaignErr)
+	<-started2
+	leader, err = e1.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "node2", leader)
+	require.NoError(t, e2.Resign(ctx))
+
+	_, err = e1.Leader(ctx)
+	assert.Equal(t, ErrNoLeader, err)
+}
@@ -0,0 +1,235 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestElection_Campaign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	held := redis.NewCmd(context.Background())
+	held.SetVal(int64(0))
+	netErr := redis.NewCmd(context.Background())
+	netErr.SetErr(context.DeadlineExceeded)
+	elected := redis.NewCmd(context.Background())
+	elected.SetVal(int64(1))
+	lost := redis.NewCmd(context.Background())
+	lost.SetVal(int64(0))
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+			Return(held),
+		// 网络错误不会中断竞选
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+			Return(netErr),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+			Return(elected),
+		// 锁被别人拿走了
+		cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"leader", "{leader}:owner"}, gomock.Any()).
+			Return(lost),
+	)
+
+	started := make(chan context.Context, 1)
+	stopped := make(chan struct{})
+	client := NewClient(cmd)
+	e := client.NewElection("leader", "node1", time.Millisecond*300, LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			started <- ctx
+		},
+		OnStoppedLeading: func() {
+			close(stopped)
+		},
+	}, WithCampaignInterval(time.Millisecond*10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	require.NoError(t, e.Campaign(ctx))
+	assert.True(t, e.IsLeader())
+	leaderCtx := <-started
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal("没有感知到失去 leader 身份")
+	}
+	assert.ErrorIs(t, context.Cause(leaderCtx), ErrLockLost)
+	assert.False(t, e.IsLeader())
+	// 已经不是 leader 了
+	assert.NoError(t, e.Resign(ctx))
+}
+
+func TestElection_CampaignAfterStoppedLeading(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	ok := redis.NewCmd(context.Background())
+	ok.SetVal(int64(1))
+	lost := redis.NewCmd(context.Background())
+	lost.SetVal(int64(0))
+	var stoppedLeading atomic.Bool
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+		Return(ok)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"leader", "{leader}:owner"}, gomock.Any()).
+		Return(lost)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+			// 上一任的 OnStoppedLeading 执行完之前不能开始新的一任
+			assert.True(t, stoppedLeading.Load())
+			return ok
+		})
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"leader", "{leader}:owner"}, gomock.Any()).
+		AnyTimes().Return(ok)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"leader", "{leader}:owner"}, gomock.Any()).
+		Return(ok)
+
+	stopping := make(chan struct{})
+	release := make(chan struct{})
+	client := NewClient(cmd)
+	e := client.NewElection("leader", "node1", time.Millisecond*300, LeaderCallbacks{
+		OnStoppedLeading: func() {
+			if stoppedLeading.Load() {
+				return
+			}
+			close(stopping)
+			<-release
+			stoppedLeading.Store(true)
+		},
+	}, WithCampaignInterval(time.Millisecond*10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	require.NoError(t, e.Campaign(ctx))
+	<-stopping
+
+	campaigned := make(chan error, 1)
+	go func() {
+		campaigned <- e.Campaign(ctx)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	require.NoError(t, <-campaigned)
+	assert.True(t, e.IsLeader())
+	assert.NoError(t, e.Resign(ctx))
+}
+
+func TestElection_CampaignTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	held := redis.NewCmd(context.Background())
+	held.SetVal(int64(0))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"leader", "{leader}:owner", "{leader}:fencing"}, gomock.Any()).
+		MinTimes(1).Return(held)
+
+	client := NewClient(cmd)
+	e := client.NewElection("leader", "node1", time.Minute, LeaderCallbacks{},
+		WithCampaignInterval(time.Millisecond*10))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, e.Campaign(ctx))
+	assert.False(t, e.IsLeader())
+}
+
+func TestElection_Leader(t *testing.T) {
+	testCases := []struct {
+		name       string
+		mock       func(ctrl *gomock.Controller) redis.Cmdable
+		wantLeader string
+		wantErr    error
+	}{
+		{
+			name: "no leader",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().Get(gomock.Any(), "leader").
+					Return(redis.NewStringResult("", redis.Nil))
+				return cmd
+			},
+			wantErr: ErrNoLeader,
+		},
+		{
+			name: "leader",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().Get(gomock.Any(), "leader").
+					Return(redis.NewStringResult("node#1#e6c2a1f0", nil))
+				return cmd
+			},
+			wantLeader: "node#1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			e := client.NewElection("leader", "node1", time.Minute, LeaderCallbacks{})
+			leader, err := e.Leader(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantLeader, leader)
+		})
+	}
+}
+
+func TestElection_CampaignError(t *testing.T) {
+	wrongType := errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	noPerm := errors.New("NOPERM this user has no permissions to run the 'evalsha' command")
+	testCases := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{
+			// 不是网络错误，重试也没用
+			name:    "wrong type",
+			err:     wrongType,
+			wantErr: wrongType,
+		},
+		{
+			name:    "no permission",
+			err:     noPerm,
+			wantErr: noPerm,
+		},
+		{
+			// 网络错误一直重试到 ctx 过期
+			name:    "connection reset",
+			err:     &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:    "eof",
+			err:     io.EOF,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := redismock.NewMockCmdable(ctrl)
+			res := redis.NewCmd(context.Background())
+			res.SetErr(tc.err)
+			cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).
+				MinTimes(1).Return(res)
+
+			client := NewClient(cmd)
+			e := client.NewElection("leader", "node1", time.Minute, LeaderCallbacks{},
+				WithCampaignInterval(time.Millisecond*10))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+			assert.Equal(t, tc.wantErr, e.Campaign(ctx))
+			assert.False(t, e.IsLeader())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed lua/fair_lock.lua
+var luaFairLockSrc string
+var luaFairLock = redis.NewScript(luaFairLockSrc)
+
+//go:embed lua/fair_leave.lua
+var luaFairLeaveSrc string
+var luaFairLeave = redis.NewScript(luaFairLeaveSrc)
+
+// fairQueueKeys 公平锁的等待队列和等待者超时时间
+func fairQueueKeys(key string) []string {
+	return []string{sameSlotKey(key, ":fair:queue"), sameSlotKey(key, ":fair:timeout")}
+}
+
+// TryLockFair 尝试加公平锁，只有锁空闲并且没有人在排队的时候才能成功，失败的时候不会排队
+func (c *Client) TryLockFair(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	val := uuid.New().String()
+	start := time.Now()
+	token, err := c.attempt(ctx, key, 1, func(ctx context.Context) (int64, error) {
+		return c.tryLockFair(ctx, key, val, expiration, false)
+	})
+	if err == nil && token <= 0 {
+		err = ErrFailedToPreemptLock
+	}
+	c.observeAcquire(ctx, key, 1, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return c.newLock(key, val, expiration, token, start, nil), nil
+}
+
+// LockFair 加公平锁，按照到达的先后顺序拿到锁
+// 加锁失败的时候会在 Redis 里面排队，之后每一次重试都是在确认自己还在排队，
+// 等待者超过 expiration 没有重试就认为它已经崩溃了，会被移出队列，
+// 所以重试的间隔要小于 expiration。
+// 拿到的锁和 Lock 拿到的锁一样，但是同一个 key 不要混用公平锁和非公平锁
+func (c *Client) LockFair(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*Lock, error) {
+	val := uuid.New().String()
+	var wake <-chan struct{}
+	if c.notifier != nil {
+		var cancel func()
+		wake, cancel = c.notifier.wait(ctx, key)
+		defer cancel()
+	}
+	begin := time.Now()
+	attempts := 0
+	var token int64
+	var start time.Time
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), wake, func(ctx context.Context) (bool, error) {
+		var err error
+		attempts++
+		start = time.Now()
+		token, err = c.attempt(ctx, key, attempts, func(ctx context.Context) (int64, error) {
+			return c.tryLockFair(ctx, key, val, expiration, true)
+		})
+		return token > 0, err
+	})
+	c.observeAcquire(ctx, key, attempts, begin, err)
+	if err != nil {
+		// 放弃排队，不然后面的人要等到超时才能轮到
+		ctx2, cancel := cleanupContext(timeout)
+		_ = luaFairLeave.Run(ctx2, c.client, fairQueueKeys(key), val).Err()
+		cancel()
+		return nil, err
+	}
+	return c.newLock(key, val, expiration, token, start, nil), nil
+}
+
+// tryLockFair 加锁成功返回 fencing token，开启了 WithoutFencing 的时候返回 1，失败返回 0
+func (c *Client) tryLockFair(ctx context.Context, key string, val string,
+	expiration time.Duration, enqueue bool) (int64, error) {
+	enqueueFlag := "0"
+	if enqueue {
+		enqueueFlag = "1"
+	}
+	keys := append([]string{key}, fairQueueKeys(key)...)
+	keys = append(keys, ownerKey(key))
+	if c.fencing {
+		keys = append(keys, fencingKey(key))
+	}
+	args := append([]any{val, expiration.Milliseconds(), enqueueFlag}, ownerArgs(c.owner)...)
+	return luaFairLock.Run(ctx, c.client, keys, args...).Int64()
+}
@@ -0,0 +1,93 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_e2e_LockFair(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithOwnerLabel("fair"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "fair-key1"
+
+	holder, err := client.TryLockFair(ctx, key, time.Minute)
+	require.NoError(t, err)
+	// 公平锁和普通的锁一样可以查看持有者信息
+	info, err := client.Inspect(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, holder.value, info.Value)
+	assert.Equal(t, "fair", info.Owner.Label)
+
+	// 三个等待者依次到达
+	var lock sync.Mutex
+	order := make([]int, 0, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, err := client.LockFair(ctx, key, time.Minute, time.Second,
+				retry.FixedInterval(time.Millisecond*10, 1000))
+			require.NoError(t, err)
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+			time.Sleep(time.Millisecond * 50)
+			require.NoError(t, l.Unlock(context.Background()))
+		}(i)
+		time.Sleep(time.Millisecond * 50)
+	}
+	// 有人在排队，TryLockFair 不能插队
+	_, err = client.TryLockFair(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	require.NoError(t, holder.Unlock(ctx))
+	wg.Wait()
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+	cnt, err := rdb.ZCard(ctx, fairQueueKeys(key)[0]).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+}
+
+func Test_e2e_LockFair_DeadWaiter(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "fair-key2"
+	keys := fairQueueKeys(key)
+
+	// 模拟一个排在队头但是已经崩溃了的等待者
+	require.NoError(t, rdb.ZAdd(ctx, keys[0], redis.Z{Score: 0, Member: "dead"}).Err())
+	require.NoError(t, rdb.ZAdd(ctx, keys[1], redis.Z{
+		Score:  float64(time.Now().Add(time.Millisecond * 300).UnixMilli()),
+		Member: "dead",
+	}).Err())
+
+	_, err := client.TryLockFair(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	// 等待者超时之后被移出队列，后面的人可以拿到锁
+	l, err := client.LockFair(ctx, key, time.Minute, time.Second,
+		retry.FixedInterval(time.Millisecond*50, 100))
+	require.NoError(t, err)
+	require.NoError(t, l.Unlock(ctx))
+	for _, k := range keys {
+		require.NoError(t, rdb.Del(ctx, k).Err())
+	}
+}
@@ -0,0 +1,162 @@
+package redis_lock
+
+import (
+	"context"
+	"fmt"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// fairLockArgs 公平锁的参数，和 lockArgs 一样带上持有者信息
+func fairLockArgs(enqueue string) []any {
+	return []any{gomock.Any(), int64(60000), enqueue,
+		ownerFieldHostname, gomock.Any(), ownerFieldPID, gomock.Any(),
+		ownerFieldAcquiredAt, gomock.Any(), ownerFieldLabel, gomock.Any()}
+}
+
+func TestClient_TryLockFair(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "held or others waiting",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				// TryLockFair 不排队
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"},
+					fairLockArgs("0")...).Return(res)
+				return cmd
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl), WithoutWatchdog())
+			lock, err := client.TryLockFair(context.Background(), "key1", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "key1", lock.key)
+			assert.NotEmpty(t, lock.value)
+		})
+	}
+}
+
+func TestClient_LockFair(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		timeout time.Duration
+		wantErr error
+	}{
+		{
+			name:    "wait in queue and success",
+			timeout: time.Second,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				held := redis.NewCmd(context.Background())
+				held.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"},
+					fairLockArgs("1")...).Times(2).Return(held)
+				ok := redis.NewCmd(context.Background())
+				ok.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"},
+					fairLockArgs("1")...).Return(ok)
+				return cmd
+			},
+		},
+		{
+			name: "give up and leave queue",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				held := redis.NewCmd(context.Background())
+				held.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"},
+					fairLockArgs("1")...).Times(3).Return(held)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLeave.Hash(),
+					[]string{"{key1}:fair:queue", "{key1}:fair:timeout"}, gomock.Any()).Return(res)
+				return cmd
+			},
+			timeout: time.Second,
+			wantErr: fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+		},
+		{
+			// 每一次加锁不设置超时，离开队列的时候依旧要能访问 Redis
+			name: "no timeout, give up and leave queue",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				held := redis.NewCmd(context.Background())
+				held.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(),
+					[]string{"key1", "{key1}:fair:queue", "{key1}:fair:timeout", "{key1}:owner", "{key1}:fencing"},
+					fairLockArgs("1")...).Times(3).Return(held)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaFairLeave.Hash(),
+					[]string{"{key1}:fair:queue", "{key1}:fair:timeout"}, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+						assert.NoError(t, ctx.Err())
+						res := redis.NewCmd(context.Background())
+						res.SetVal(int64(1))
+						return res
+					})
+				return cmd
+			},
+			wantErr: fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl), WithoutWatchdog())
+			lock, err := client.LockFair(context.Background(), "key1", time.Minute, tc.timeout,
+				retry.FixedInterval(time.Millisecond, 2))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "key1", lock.key)
+		})
+	}
+}
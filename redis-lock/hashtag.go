@@ -0,0 +1,40 @@
+package redis_lock
+
+import "strings"
+
+// HashTag 按照 Redis Cluster 的规则取出 key 的 hash tag，
+// 也就是第一个 { 和它之后第一个 } 之间的内容，没有或者为空的时候返回 ""
+// 带有同样 hash tag 的 key 在 Redis Cluster 下会落在同一个槽上，在 redis.Ring 下会落在同一个分片上
+func HashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return ""
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return ""
+	}
+	return key[start+1 : start+1+end]
+}
+
+// WithHashTag 给每一个 key 加上同样的 hash tag，变成 {tag}:key
+// LockMulti 在 Redis Cluster 或者 redis.Ring 下要求所有的 key 带有同样的 hash tag，比如
+// client.LockMulti(ctx, WithHashTag("account", "1", "2"), ...)
+func WithHashTag(tag string, keys ...string) []string {
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, "{"+tag+"}:"+key)
+	}
+	return res
+}
+
+// sameSlotKey 派生出来的、要和 key 在同一个脚本里面使用的 key，比如 fencing token 的计数器
+// key 没有 hash tag 的时候，用整个 key 作为派生 key 的 hash tag，
+// 这样派生 key 和 key 本身在 Redis Cluster 下会落在同一个槽上
+func sameSlotKey(key string, suffix string) string {
+	if HashTag(key) != "" || strings.IndexByte(key, '}') >= 0 {
+		// key 里面有 } 的时候没法把整个 key 作为 hash tag，只能要求用户自己带上 hash tag
+		return key + suffix
+	}
+	return "{" + key + "}" + suffix
+}
@@ -0,0 +1,75 @@
+package redis_lock
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHashTag(t *testing.T) {
+	testCases := []struct {
+		key  string
+		want string
+	}{
+		{key: "account", want: ""},
+		{key: "{account}:1", want: "account"},
+		{key: "user:{account}:{1}", want: "account"},
+		{key: "{}:account", want: ""},
+		{key: "{account", want: ""},
+		{key: "a}{b}", want: "b"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			assert.Equal(t, tc.want, HashTag(tc.key))
+		})
+	}
+}
+
+func TestWithHashTag(t *testing.T) {
+	keys := WithHashTag("account", "1", "2")
+	assert.Equal(t, []string{"{account}:1", "{account}:2"}, keys)
+	for _, key := range keys {
+		assert.Equal(t, "account", HashTag(key))
+	}
+}
+
+func TestSameSlotKey(t *testing.T) {
+	testCases := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "no hash tag",
+			key:  "order",
+			want: "{order}:fencing",
+		},
+		{
+			name: "hash tag",
+			key:  "{order}:1",
+			want: "{order}:1:fencing",
+		},
+		{
+			name: "unmatched brace",
+			key:  "order}",
+			want: "order}:fencing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sameSlotKey(tc.key, ":fencing")
+			assert.Equal(t, tc.want, got)
+			if HashTag(tc.key) != "" || tc.key == "order" {
+				// 派生的 key 和原来的 key 落在同一个槽上
+				assert.Equal(t, slotKey(tc.key), slotKey(got))
+			}
+		})
+	}
+}
+
+// slotKey Redis Cluster 实际用来计算槽的部分
+func slotKey(key string) string {
+	if tag := HashTag(key); tag != "" {
+		return tag
+	}
+	return key
+}
@@ -0,0 +1,94 @@
+package redis_lock
+
+import (
+	"context"
+	"sync"
+)
+
+// WithLocalCoalescing 开启本地排队
+// 同一个进程里面抢同一把锁的 goroutine 先在本地按照 key 排队，
+// 同一时刻只有排在最前面的那一个去访问 Redis，拿到锁之后一直占着本地的位置，直到锁释放或者丢失。
+// 本地竞争很激烈的时候可以大幅减少对 Redis 的请求。
+//   - Lock 在本地排队的时候只受 ctx 控制，拿到本地的位置之后才开始按照重试策略访问 Redis
+//   - TryLock 发现本地有人持有或者正在抢这把锁的时候直接返回 ErrFailedToPreemptLock
+//   - 关闭了看门狗的时候，锁过期了本地的位置也不会让出来，一定要调用 Unlock
+//
+// 只对 Lock、TryLock 以及对应的 WithOptions 版本生效，Resume 不会排队
+func WithLocalCoalescing() ClientOption {
+	return func(c *Client) {
+		c.local = newLocalLocks()
+	}
+}
+
+// localLocks 进程内按照 key 划分的互斥锁
+// 每个 key 一个条目，没有人持有也没有人排队的时候就删掉，不会无限增长
+type localLocks struct {
+	mu      sync.Mutex
+	entries map[string]*localLockEntry
+}
+
+type localLockEntry struct {
+	// 容量为 1，放进去代表拿到了锁
+	ch chan struct{}
+	// 持有和排队的人数
+	refs int
+}
+
+func newLocalLocks() *localLocks {
+	return &localLocks{entries: make(map[string]*localLockEntry)}
+}
+
+// lock 阻塞直到拿到 key 的本地锁或者 ctx 过期，返回的 release 用来释放本地锁，重复调用是安全的
+func (l *localLocks) lock(ctx context.Context, key string) (func(), error) {
+	entry := l.acquire(key)
+	select {
+	case entry.ch <- struct{}{}:
+		return l.releaseFunc(key, entry), nil
+	case <-ctx.Done():
+		l.unref(key, entry)
+		return nil, ctx.Err()
+	}
+}
+
+// tryLock 尝试拿 key 的本地锁，拿不到的时候立刻返回 false
+func (l *localLocks) tryLock(key string) (func(), bool) {
+	entry := l.acquire(key)
+	select {
+	case entry.ch <- struct{}{}:
+		return l.releaseFunc(key, entry), true
+	default:
+		l.unref(key, entry)
+		return nil, false
+	}
+}
+
+func (l *localLocks) acquire(key string) *localLockEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &localLockEntry{ch: make(chan struct{}, 1)}
+		l.entries[key] = entry
+	}
+	entry.refs++
+	return entry
+}
+
+func (l *localLocks) releaseFunc(key string, entry *localLockEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-entry.ch
+			l.unref(key, entry)
+		})
+	}
+}
+
+func (l *localLocks) unref(key string, entry *localLockEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(l.entries, key)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countHook 统计发给 Redis 的命令数量
+type countHook struct {
+	cnt atomic.Int64
+}
+
+func (h *countHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *countHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.cnt.Add(1)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *countHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.cnt.Add(int64(len(cmds)))
+		return next(ctx, cmds)
+	}
+}
+
+// BenchmarkLock_LocalCoalescing 200 个 goroutine 抢同一把锁，
+// redis-calls/op 是每次加锁加释放锁平均发给 Redis 的命令数量
+func BenchmarkLock_LocalCoalescing(b *testing.B) {
+	testCases := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{
+			name: "without coalescing",
+			opts: []ClientOption{WithoutWatchdog()},
+		},
+		{
+			name: "with coalescing",
+			opts: []ClientOption{WithoutWatchdog(), WithLocalCoalescing()},
+		},
+	}
+	for _, tc := range testCases {
+		b.Run(tc.name, func(b *testing.B) {
+			rdb := redis.NewClient(&redis.Options{
+				Addr: "localhost:6379",
+			})
+			defer rdb.Close()
+			hook := &countHook{}
+			rdb.AddHook(hook)
+			client := NewClient(rdb, tc.opts...)
+			ctx := context.Background()
+			if err := client.LoadScripts(ctx); err != nil {
+				b.Fatal(err)
+			}
+			hook.cnt.Store(0)
+			b.SetParallelism(200)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					l, err := client.Lock(ctx, "bench_coalescing_key", time.Minute, time.Second,
+						retry.FixedInterval(time.Millisecond, 0))
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					if err = l.Unlock(ctx); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(hook.cnt.Load())/float64(b.N), "redis-calls/op")
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLocalLocks(t *testing.T) {
+	l := newLocalLocks()
+	release1, err := l.lock(context.Background(), "key1")
+	require.NoError(t, err)
+
+	_, ok := l.tryLock("key1")
+	assert.False(t, ok)
+	// 不同的 key 互不影响
+	release2, ok := l.tryLock("key2")
+	require.True(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err = l.lock(ctx, "key1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	acquired := make(chan func())
+	go func() {
+		release, err := l.lock(context.Background(), "key1")
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("锁还没有释放")
+	case <-time.After(time.Millisecond * 50):
+	}
+	release1()
+	// 重复释放不会影响下一个持有者
+	release1()
+	release3 := <-acquired
+	_, ok = l.tryLock("key1")
+	assert.False(t, ok)
+
+	release2()
+	release3()
+	// 没人用的条目会被删掉
+	assert.Empty(t, l.entries)
+}
+
+func TestClient_LocalCoalescing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	evalRes := func(val int64) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal(val)
+		return res
+	}
+	// 三个人抢锁，只有拿到本地锁的人会访问 Redis
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Times(2).Return(evalRes(1))
+	var client *Client
+	cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+			// Redis 上的锁释放之前，本地锁还没有放行
+			_, ok := client.local.tryLock("key1")
+			assert.False(t, ok)
+			return evalRes(1)
+		})
+	client = NewClient(cmd, WithLocalCoalescing(), WithoutWatchdog())
+	ctx := context.Background()
+
+	l1, err := client.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	_, err = client.TryLock(ctx, "key1", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	locked := make(chan *Lock)
+	go func() {
+		l, err := client.Lock(ctx, "key1", time.Minute, time.Second, nil)
+		if err == nil {
+			locked <- l
+		}
+	}()
+	time.Sleep(time.Millisecond * 50)
+	require.NoError(t, l1.Unlock(ctx))
+	l2 := <-locked
+	require.NoError(t, l2.Unlock(ctx))
+	assert.Empty(t, client.local.entries)
+}
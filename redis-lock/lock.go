@@ -5,173 +5,578 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
-	"github.com/google/uuid"
+	"github.com/Jared-lu/GXT/retry"
 	"github.com/redis/go-redis/v9"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
 	ErrFailedToPreemptLock = errors.New("failed to preempt lock")
 	ErrLockNotHeld         = errors.New("lock not held")
+	// ErrLockLost 锁在持有期间丢失了，会同时包装丢失的具体原因
+	ErrLockLost = errors.New("lock lost")
 )
 
 //go:embed lua/unlock.lua
-var luaUnlock string
+var luaUnlockSrc string
+var luaUnlock = redis.NewScript(luaUnlockSrc)
 
 //go:embed lua/refresh.lua
-var luaRefresh string
+var luaRefreshSrc string
+var luaRefresh = redis.NewScript(luaRefreshSrc)
 
 //go:embed lua/lock.lua
-var luaLock string
+var luaLockSrc string
+var luaLock = redis.NewScript(luaLockSrc)
+
+//go:embed lua/ttl.lua
+var luaTTLSrc string
+var luaTTL = redis.NewScript(luaTTLSrc)
 
 type Client struct {
 	client redis.Cmdable
+	// 不为 nil 的时候，等待锁的人会在锁释放的时候被立刻唤醒
+	notifier *notifier
+	// 释放锁的时候是否发消息，见 WithPublishOnRelease
+	publish  bool
+	watchdog watchdogConfig
+	// 加锁的时候写入的持有者信息，AcquiredAt 在加锁的时候填
+	owner Owner
+	// 签名 Lock.Token 的密钥
+	tokenSecret []byte
+	// 不为 nil 的时候，同一个进程里面的人先在本地排队，见 WithLocalCoalescing
+	local    *localLocks
+	observer Observer
+	// 是否生成 fencing token，默认开启，见 WithoutFencing
+	fencing bool
+}
+
+type ClientOption func(c *Client)
+
+// WithOwnerLabel 持有者信息里面的标签，比如服务名或者任务名，Inspect 和 ListLocks 的时候可以看到
+func WithOwnerLabel(label string) ClientOption {
+	return func(c *Client) {
+		c.owner.Label = label
+	}
+}
+
+// WithoutFencing 关闭 fencing token，关闭之后 Lock.FencingToken 总是 0
+// 每个 key 会在 Redis 里面多一个不会过期的计数器，锁的 key 很多、并且不会重复使用的时候可以关闭
+func WithoutFencing() ClientOption {
+	return func(c *Client) {
+		c.fencing = false
+	}
+}
+
+// WithNotification 开启释放通知
+// 释放锁的时候会往这把锁的频道发一条消息，Lock 阻塞等待的时候订阅这个频道，
+// 收到消息就立刻重试，不用等到下一次重试的时间。
+// 锁过期或者消息丢失的时候没有通知，依旧按照 RetryStrategy 兜底重试。
+// redis.Ring 下订阅连接只会连到一个分片上，收不到其它分片的通知，不要开启
+// 开启之后释放锁的时候也会发消息，相当于同时开启了 WithPublishOnRelease
+func WithNotification(sub Subscriber) ClientOption {
+	return func(c *Client) {
+		c.notifier = newNotifier(sub)
+		c.publish = true
+	}
 }
 
-func NewClient(client redis.Cmdable) *Client {
-	return &Client{client: client}
+// WithPublishOnRelease 释放锁的时候发消息，但是自己不订阅
+// 等待的人可能在别的进程里面，只要有一个进程开启了 WithNotification，
+// 所有会释放这些锁的进程都要开启 WithPublishOnRelease 或者 WithNotification，不然那边收不到通知
+func WithPublishOnRelease() ClientOption {
+	return func(c *Client) {
+		c.publish = true
+	}
 }
 
+// NewClient client 可以是 redis.Client、redis.ClusterClient 或者 redis.Ring
+// 一个脚本里面用到的 key 会自动落在同一个槽上，见 HashTag
+func NewClient(client redis.Cmdable, opts ...ClientOption) *Client {
+	hostname, _ := os.Hostname()
+	c := &Client{
+		client:   client,
+		watchdog: watchdogConfig{enabled: true},
+		owner:    Owner{Hostname: hostname, PID: os.Getpid()},
+		fencing:  true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.observer == nil {
+		c.observer = NopObserver{}
+	}
+	return c
+}
+
+// Lock 加锁，加锁失败时按照 factory 创建的重试策略重试，factory 为 nil 的时候只尝试一次
+// 每次调用都会创建一个新的策略，所以同一个 factory 可以在多次加锁之间共享
+// timeout 是每一次加锁的超时时间，<= 0 的时候不设置超时。
+// 更多的参数见 LockWithOptions
 func (c *Client) Lock(ctx context.Context, key string,
-	expiration time.Duration, timeout time.Duration, retry RetryStrategy) (*Lock, error) {
-	var ticker *time.Ticker
-	val := uuid.New().String()
-	for {
-		ctx2, cancel := context.WithTimeout(ctx, timeout)
-		res, err := c.client.Eval(ctx2, luaLock, []string{key}, val, expiration.Seconds()).Int64()
-		cancel()
-		if err != nil || errors.Is(err, context.DeadlineExceeded) {
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*Lock, error) {
+	return c.acquire(ctx, key, lockOptions{
+		expiration:     expiration,
+		timeout:        timeout,
+		retry:          factory,
+		valueGenerator: newLockValue,
+		owner:          c.owner,
+	})
+}
+
+// acquire 加锁，o 已经校验过了
+func (c *Client) acquire(ctx context.Context, key string, o lockOptions) (*Lock, error) {
+	val := o.valueGenerator()
+	if val == "" {
+		return nil, fmt.Errorf("%w: 锁的 value 不能为空", ErrInvalidLockOption)
+	}
+	begin := time.Now()
+	attempts := 0
+	var release func()
+	if c.local != nil {
+		var err error
+		release, err = c.local.lock(ctx, key)
+		if err != nil {
+			c.observeAcquire(ctx, key, attempts, begin, err)
 			return nil, err
 		}
-
-		if res == 1 {
-			return &Lock{
-				client:     c.client,
-				key:        key,
-				value:      val,
-				expiration: expiration,
-				unlockChan: make(chan struct{}, 1),
-			}, nil
+	}
+	var wake <-chan struct{}
+	if c.notifier != nil {
+		var cancel func()
+		wake, cancel = c.notifier.wait(ctx, key)
+		defer cancel()
+	}
+	retry := newRetryStrategy(o.retry)
+	var token int64
+	var start time.Time
+	err := retryLock(ctx, o.timeout, retry, wake, func(ctx context.Context) (bool, error) {
+		var err error
+		attempts++
+		start = time.Now()
+		token, err = c.attempt(ctx, key, attempts, func(ctx context.Context) (int64, error) {
+			return c.lock(ctx, key, val, o.expiration, o.owner)
+		})
+		return token > 0, err
+	})
+	c.observeAcquire(ctx, key, attempts, begin, err)
+	if err != nil {
+		if release != nil {
+			release()
 		}
+		return nil, err
+	}
+	return c.newLock(key, val, o.expiration, token, start, release), nil
+}
 
-		// 这里要执行重试
-		// 我怎么知道还能不能重试，怎么重试，重试间隔是多久？
-		// 调用方传入RetryStrategy来决定重试策略
-		interval, ok := retry.Next()
-		if !ok {
-			return nil, fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock)
+// cleanupContext 放弃加锁之后用来清理的 context
+// 调用方的 ctx 可能已经过期了，所以用一个新的 context。
+// timeout 是每一次加锁的超时时间，<= 0 的时候没有超时，清理就使用 defaultUnlockTimeout
+func cleanupContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = defaultUnlockTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// retryLock 按照重试策略反复尝试加锁
+// try 返回 true 代表加锁成功，返回 error 会直接中断重试
+// timeout 是每一次尝试的超时时间，<= 0 的时候不设置超时
+// wake 有信号的时候会立刻重试，这一次重试不消耗重试策略，
+// 重试策略给出的间隔依旧是两次重试之间的最长等待时间
+func retryLock(ctx context.Context, timeout time.Duration, retry RetryStrategy,
+	wake <-chan struct{}, try func(ctx context.Context) (bool, error)) error {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
 		}
-		if ticker == nil {
-			ticker = time.NewTicker(interval)
-		} else {
-			ticker.Reset(interval)
+	}()
+	woken := false
+	for {
+		ctx2, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx2, cancel = context.WithTimeout(ctx, timeout)
+		}
+		ok, err := try(ctx2)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		// 被唤醒的重试失败了，继续等上一次的重试间隔，不重新计算
+		if !woken {
+			// 这里要执行重试
+			// 我怎么知道还能不能重试，怎么重试，重试间隔是多久？
+			// 调用方传入RetryStrategy来决定重试策略
+			if retry == nil {
+				return fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock)
+			}
+			interval, ok := retry.Next()
+			if !ok {
+				return fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock)
+			}
+			if timer == nil {
+				timer = time.NewTimer(interval)
+			} else {
+				timer.Reset(interval)
+			}
 		}
 		select {
-		case <-ticker.C:
+		case <-timer.C:
+			woken = false
+		case <-wake:
+			woken = true
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		}
 	}
 }
 
+// TryLock 尝试加锁，锁被别人持有的时候返回 ErrFailedToPreemptLock
+// 更多的参数见 TryLockWithOptions
 func (c *Client) TryLock(ctx context.Context,
 	key string, expiration time.Duration) (*Lock, error) {
-	val := uuid.New().String()
+	return c.tryAcquire(ctx, key, lockOptions{
+		expiration:     expiration,
+		valueGenerator: newLockValue,
+		owner:          c.owner,
+	})
+}
+
+// tryAcquire 尝试加锁，o 已经校验过了
+func (c *Client) tryAcquire(ctx context.Context, key string, o lockOptions) (*Lock, error) {
+	val := o.valueGenerator()
+	if val == "" {
+		return nil, fmt.Errorf("%w: 锁的 value 不能为空", ErrInvalidLockOption)
+	}
+	var release func()
+	if c.local != nil {
+		var ok bool
+		release, ok = c.local.tryLock(key)
+		if !ok {
+			// 同一个进程里面有人持有或者正在抢这把锁，不用再访问 Redis
+			c.observeAcquire(ctx, key, 0, time.Now(), ErrFailedToPreemptLock)
+			return nil, ErrFailedToPreemptLock
+		}
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
 	// 设置特定键值对成功，就代表加锁成功
-	ok, err := c.client.SetNX(ctx, key, val, expiration).Result()
+	start := time.Now()
+	token, err := c.attempt(ctx, key, 1, func(ctx context.Context) (int64, error) {
+		return c.lock(ctx, key, val, o.expiration, o.owner)
+	})
+	if err == nil && token <= 0 {
+		// 别人抢到了锁
+		err = ErrFailedToPreemptLock
+	}
+	c.observeAcquire(ctx, key, 1, start, err)
 	if err != nil {
 		// 如果是超时，会进来这里
+		if release != nil {
+			release()
+		}
 		return nil, err
 	}
-	if !ok {
-		// 别人抢到了锁
-		return nil, ErrFailedToPreemptLock
+
+	return c.newLock(key, val, o.expiration, token, start, release), nil
+}
+
+// attempt 调用 lock 访问一次 Redis 加锁，并且通知 Observer
+// lock 和 Client.lock 一样，加锁成功返回 fencing token，失败返回 0
+func (c *Client) attempt(ctx context.Context, key string, attempt int,
+	lock func(ctx context.Context) (int64, error)) (int64, error) {
+	start := time.Now()
+	token, err := lock(ctx)
+	c.observer.OnAttempt(ctx, AttemptEvent{
+		Key:       key,
+		Attempt:   attempt,
+		Latency:   time.Since(start),
+		Contended: err == nil && token <= 0,
+		Err:       err,
+	})
+	return token, err
+}
+
+func (c *Client) observeAcquire(ctx context.Context, key string, attempts int, begin time.Time, err error) {
+	c.observer.OnAcquire(ctx, AcquireEvent{
+		Key:      key,
+		Attempts: attempts,
+		Retries:  max(attempts-1, 0),
+		Latency:  time.Since(begin),
+		Err:      err,
+	})
+}
+
+// lock 加锁成功返回 fencing token，开启了 WithoutFencing 的时候返回 1，失败返回 0
+// 同时写入持有者信息
+func (c *Client) lock(ctx context.Context, key string, val string,
+	expiration time.Duration, owner Owner) (int64, error) {
+	keys := []string{key, ownerKey(key)}
+	if c.fencing {
+		keys = append(keys, fencingKey(key))
 	}
+	args := append([]any{val, expiration.Milliseconds()}, ownerArgs(owner)...)
+	return luaLock.Run(ctx, c.client, keys, args...).Int64()
+}
 
-	return &Lock{
-		client:     c.client,
-		key:        key,
-		value:      val,
-		expiration: expiration,
-		unlockChan: make(chan struct{}, 1),
-	}, nil
+// ownerArgs 写入持有者信息的 hash 的 field value，AcquiredAt 取当前时间
+func ownerArgs(owner Owner) []any {
+	return []any{
+		ownerFieldHostname, owner.Hostname,
+		ownerFieldPID, owner.PID,
+		ownerFieldAcquiredAt, strconv.FormatInt(time.Now().UnixMilli(), 10),
+		ownerFieldLabel, owner.Label,
+	}
+}
+
+// fencingKey 记录 fencing token 的计数器，不会过期
+func fencingKey(key string) string {
+	return sameSlotKey(key, ":fencing")
+}
+
+// ownerKey 记录持有者信息的 hash，和锁一起续约、一起释放
+func ownerKey(key string) string {
+	return sameSlotKey(key, ":owner")
 }
 
 type Lock struct {
-	client redis.Cmdable
+	backend lockBackend
 	// key + value 才是锁的唯一标识
 	key        string
 	value      string
 	expiration time.Duration
+	// 加锁时拿到的 fencing token，同一个 key 每次加锁都会递增
+	fencingToken int64
+	// 锁释放或者丢失的时候关闭，用来停止看门狗
 	unlockChan chan struct{}
+	stopOnce   sync.Once
+	// 锁还持有的时候有效，锁丢失或者释放之后取消
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	// 锁丢失的时候会收到丢失的原因
+	lost chan error
+	// 锁在 Redis 上的过期时间的本地估算，UnixNano
+	// 从发出加锁或者续约请求的时间开始算，所以一定不会晚于真实的过期时间
+	leaseDeadline atomic.Int64
+	// 看门狗是否已经启动
+	watching atomic.Bool
+	// 持有 refreshMu 的时候才能修改 paused，以及在 paused 为 false 的时候续约
+	refreshMu sync.Mutex
+	// 为 true 的时候看门狗暂停续约，见 Client.Transfer
+	paused bool
+	// 是否已经成功释放
+	released atomic.Bool
+	// 签名 Token 的密钥，为 nil 的时候不能导出 Token
+	tokenSecret []byte
+	// 不为 nil 的时候在锁释放或者丢失之后调用，用来释放本地排队的锁
+	release func()
+	// 为 nil 的时候不通知
+	observer Observer
+	// 拿到锁的时间，用来计算持有的时长
+	acquiredAt time.Time
+}
+
+// start 是发出加锁请求的时间
+func newLock(backend lockBackend, key string, value string,
+	expiration time.Duration, start time.Time) *Lock {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	l := &Lock{
+		backend:    backend,
+		key:        key,
+		value:      value,
+		expiration: expiration,
+		unlockChan: make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		lost:       make(chan error, 1),
+		acquiredAt: time.Now(),
+	}
+	l.leaseDeadline.Store(start.Add(expiration).UnixNano())
+	return l
+}
+
+// FencingToken 加锁时拿到的 fencing token，从 1 开始递增
+// 开启了 WithoutFencing 的时候没有 token，返回 0，下游不能拿 0 做比较
+// 同一个 key 后加锁的人拿到的 token 一定更大，续约不会改变 token。
+// 下游存储可以记录见过的最大 token，拒绝 token 更小的写入，
+// 这样即使持有者因为 GC 之类的原因停顿到锁过期，它之后的写入也不会覆盖新持有者的写入
+func (l *Lock) FencingToken() int64 {
+	return l.fencingToken
+}
+
+// ValidUntil 本地估算的锁的过期时间
+// 从发出加锁或者最近一次续约成功的请求的时间开始算，
+// 网络延迟以及 Redis 服务器和本地的时钟漂移都会让真实的过期时间更晚，所以按照它做判断是安全的
+func (l *Lock) ValidUntil() time.Time {
+	return time.Unix(0, l.leaseDeadline.Load())
+}
+
+// TTL 查询锁在 Redis 上剩余的过期时间，锁已经不是自己的时候返回 ErrLockNotHeld
+func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
+	res, err := l.backend.ttl(ctx, l.key, l.value)
+	if err != nil {
+		return 0, err
+	}
+	if res < 0 {
+		return 0, ErrLockNotHeld
+	}
+	return time.Duration(res) * time.Millisecond, nil
+}
+
+// Context 锁还持有的时候有效的 context
+// 锁丢失或者释放之后会被取消，可以用 context.Cause 拿到锁丢失的原因。
+// 业务应该用这个 context（或者从它派生的 context）执行需要锁保护的操作
+func (l *Lock) Context() context.Context {
+	return l.ctx
 }
 
-// AutoRefresh 不建议使用这个API，用户最好是自己手动控制Refresh
+// Lost 锁丢失的时候会收到丢失的原因，比如锁已经被别人拿走了，或者续约一直失败直到锁过期。
+// 主动释放锁不算丢失，这个时候 channel 会直接关闭
+func (l *Lock) Lost() <-chan error {
+	return l.lost
+}
+
+// AutoRefresh 阻塞直到锁被释放或者丢失，返回锁丢失的原因，主动释放锁的时候返回 nil
 // interval 多久续约一次
 // timeout 调用续约的超时时间
+// Deprecated: 加锁成功之后看门狗已经在后台自动续约了，使用 Context 或者 Lost 感知锁丢失。
+// 只有关闭了看门狗的时候，这个方法才会按照 interval 和 timeout 启动看门狗
 func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
-	timeoutChan := make(chan struct{}, 1)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-l.unlockChan:
-			// 用户主动释放锁
-			return nil
-		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			err := l.Refresh(ctx)
-			cancel()
-			switch {
-			// 处理error
-			case errors.Is(err, context.DeadlineExceeded):
-				// 自己给自己发信号，channel一定要带缓存的
-				timeoutChan <- struct{}{}
-				continue
-			case err == nil:
-			default:
-				return err
-			}
-		case <-timeoutChan:
-			// 尝试一次重试
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			err := l.Refresh(ctx)
-			cancel()
-			if err == nil {
-				// 重试成功
-				continue
-			}
-			return err
-		}
+	l.startWatchdog(interval, timeout)
+	<-l.unlockChan
+	select {
+	case err := <-l.lost:
+		return err
+	default:
+		return nil
 	}
 }
 
 // Refresh 用户手动续约
 func (l *Lock) Refresh(ctx context.Context) error {
-	res, err := l.client.Eval(ctx, luaRefresh, []string{l.key}, l.value, l.expiration.Seconds()).Int64()
+	return l.extend(ctx, l.expiration)
+}
+
+// Extend 续约，把锁的过期时间重新设置为 d
+// 看门狗下一次续约的时候依旧会按照加锁时的过期时间续约
+func (l *Lock) Extend(ctx context.Context, d time.Duration) error {
+	return l.extend(ctx, d)
+}
+
+func (l *Lock) extend(ctx context.Context, d time.Duration) error {
+	start := time.Now()
+	res, err := l.backend.refresh(ctx, l.key, l.value, d)
+	if err == nil && res != 1 {
+		// 不是自己的锁
+		err = ErrLockNotHeld
+	}
+	if l.observer != nil {
+		l.observer.OnRefresh(ctx, RefreshEvent{Key: l.key, Latency: time.Since(start), Err: err})
+	}
 	if err != nil {
 		return err
 	}
-	if res != 1 {
-		// 不是自己的锁
-		return ErrLockNotHeld
-	}
+	l.leaseDeadline.Store(start.Add(d).UnixNano())
 	return nil
 }
 
+// Unlock 释放锁，可以重复调用
+// 已经释放成功之后再调用会直接返回 ErrLockNotHeld
 func (l *Lock) Unlock(ctx context.Context) error {
-	res, err := l.client.Eval(ctx, luaUnlock, []string{l.key}, l.value).Int64()
-	defer func() {
-		close(l.unlockChan)
-	}()
-	if err != nil {
-		return err
-	}
-	if res != 1 {
-		// 不是自己的锁
+	// 不管能不能释放成功，用户都不再需要这把锁了，先停掉看门狗
+	l.stop(nil)
+	// 本地排队的锁要等 Redis 上的锁释放之后才能放行，
+	// 否则排在后面的人会马上访问 Redis，拿到的一定是锁被占用
+	defer l.releaseLocal()
+	if l.released.Load() {
 		return ErrLockNotHeld
 	}
-	return nil
+	res, err := l.backend.unlock(ctx, l.key, l.value)
+	if err == nil {
+		l.released.Store(true)
+		if res != 1 {
+			// 不是自己的锁
+			err = ErrLockNotHeld
+		}
+	}
+	if l.observer != nil {
+		l.observer.OnRelease(ctx, ReleaseEvent{Key: l.key, Held: time.Since(l.acquiredAt), Err: err})
+	}
+	// 网络错误的时候不确定有没有释放成功，允许用户再试一次
+	return err
+}
+
+// stop 锁释放或者丢失，只有第一次调用生效
+// lostErr 为 nil 代表用户主动释放锁
+func (l *Lock) stop(lostErr error) {
+	l.stopOnce.Do(func() {
+		if lostErr != nil && l.observer != nil {
+			l.observer.OnLost(context.Background(), LostEvent{Key: l.key, Held: time.Since(l.acquiredAt), Err: lostErr})
+		}
+		if l.lost != nil {
+			if lostErr != nil {
+				l.lost <- lostErr
+			}
+			close(l.lost)
+		}
+		if l.cancel != nil {
+			l.cancel(lostErr)
+		}
+		if l.unlockChan != nil {
+			close(l.unlockChan)
+		}
+		if lostErr != nil {
+			// 锁已经丢了，Redis 上不是自己的锁，可以直接放行本地排队的人
+			l.releaseLocal()
+		}
+	})
+}
+
+// releaseLocal 释放本地排队的锁，可以重复调用
+func (l *Lock) releaseLocal() {
+	if l.release != nil {
+		l.release()
+	}
+}
+
+// lockBackend 锁的存储，返回值和对应的 lua 脚本保持一致
+type lockBackend interface {
+	// refresh 是自己的锁的时候把过期时间设置为 expiration 并返回 1，否则返回 0
+	refresh(ctx context.Context, key string, value string, expiration time.Duration) (int64, error)
+	// unlock 是自己的锁的时候释放并返回 1，否则返回 0
+	unlock(ctx context.Context, key string, value string) (int64, error)
+	// ttl 是自己的锁的时候返回剩余的过期时间，单位毫秒，否则返回 -2
+	ttl(ctx context.Context, key string, value string) (int64, error)
+}
+
+type redisBackend struct {
+	client redis.Cmdable
+	// 见 Client.publishFlag
+	publish int
+}
+
+func (b redisBackend) refresh(ctx context.Context, key string, value string, expiration time.Duration) (int64, error) {
+	return luaRefresh.Run(ctx, b.client, []string{key, ownerKey(key)}, value, expiration.Milliseconds()).Int64()
+}
+
+func (b redisBackend) unlock(ctx context.Context, key string, value string) (int64, error) {
+	return luaUnlock.Run(ctx, b.client, []string{key, ownerKey(key)}, value, b.publish).Int64()
+}
+
+func (b redisBackend) ttl(ctx context.Context, key string, value string) (int64, error) {
+	return luaTTL.Run(ctx, b.client, []string{key}, value).Int64()
 }
 
 // Unlock 定义在Lock结构体上，用户使用起来会更接近面向对象的实现：
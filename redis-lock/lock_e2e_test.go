@@ -5,6 +5,7 @@ package redis_lock
 import (
 	"context"
 	"fmt"
+	"github.com/Jared-lu/GXT/retry"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,7 +24,7 @@ func Test_e2e_Lock(t *testing.T) {
 		key        string
 		expiration time.Duration
 		timeout    time.Duration
-		retry      RetryStrategy
+		retry      retry.Factory
 		wantErr    error
 		wantLock   *Lock
 	}{
@@ -50,7 +51,7 @@ func Test_e2e_Lock(t *testing.T) {
 			wantLock: &Lock{
 				key:        "lock-key1",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 		},
 		{
@@ -74,15 +75,12 @@ func Test_e2e_Lock(t *testing.T) {
 			key:        "lock-key3",
 			expiration: time.Minute,
 			timeout:    time.Second * 3,
-			retry: &FixedIntervalRetryStrategy{
-				Interval: time.Second,
-				MaxCnt:   5,
-			},
-			wantErr: nil,
+			retry:      retry.FixedInterval(time.Second, 5),
+			wantErr:    nil,
 			wantLock: &Lock{
 				key:        "lock-key3",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 		},
 		{
@@ -104,15 +102,12 @@ func Test_e2e_Lock(t *testing.T) {
 			key:        "lock-key4",
 			expiration: time.Minute,
 			timeout:    time.Second * 3,
-			retry: &FixedIntervalRetryStrategy{
-				Interval: time.Second,
-				MaxCnt:   5,
-			},
-			wantErr: fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+			retry:      retry.FixedInterval(time.Second, 5),
+			wantErr:    fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
 			wantLock: &Lock{
 				key:        "lock-key4",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 		},
 	}
@@ -128,7 +123,7 @@ func Test_e2e_Lock(t *testing.T) {
 			assert.Equal(t, tc.wantLock.key, lock.key)
 			assert.Equal(t, tc.wantLock.expiration, lock.expiration)
 			assert.NotEmpty(t, lock.value)
-			assert.NotNil(t, lock.client)
+			assert.NotNil(t, lock.backend)
 			tc.after(t)
 		})
 	}
@@ -202,7 +197,7 @@ func Test_e2e_TryLock(t *testing.T) {
 			assert.Equal(t, tc.wantLock.key, lock.key)
 			assert.Equal(t, tc.wantLock.expiration, lock.expiration)
 			assert.NotEmpty(t, lock.value)
-			assert.NotNil(t, lock.client)
+			assert.NotNil(t, lock.backend)
 			defer tc.after(t)
 		})
 	}
@@ -237,9 +232,9 @@ func Test_e2e_Unlock(t *testing.T) {
 				assert.Equal(t, "not my key", res)
 			},
 			lock: &Lock{
-				key:    "key1",
-				value:  "value1",
-				client: rdb,
+				key:     "key1",
+				value:   "value1",
+				backend: redisBackend{client: rdb},
 			},
 			wantErr: ErrLockNotHeld,
 		},
@@ -261,9 +256,9 @@ func Test_e2e_Unlock(t *testing.T) {
 				assert.Equal(t, int64(0), res)
 			},
 			lock: &Lock{
-				key:    "key2",
-				value:  "value2",
-				client: rdb,
+				key:     "key2",
+				value:   "value2",
+				backend: redisBackend{client: rdb},
 			},
 			wantErr: nil,
 		},
@@ -276,9 +271,9 @@ func Test_e2e_Unlock(t *testing.T) {
 
 			},
 			lock: &Lock{
-				key:    "non-exist-key",
-				value:  "value",
-				client: rdb,
+				key:     "non-exist-key",
+				value:   "value",
+				backend: redisBackend{client: rdb},
 			},
 			wantErr: ErrLockNotHeld,
 		},
@@ -332,7 +327,7 @@ func Test_e2e_Refresh(t *testing.T) {
 				key:        "key1",
 				value:      "value1",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 			wantErr: ErrLockNotHeld,
 		},
@@ -360,7 +355,7 @@ func Test_e2e_Refresh(t *testing.T) {
 				key:        "key2",
 				value:      "value2",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 			wantErr: nil,
 		},
@@ -376,7 +371,7 @@ func Test_e2e_Refresh(t *testing.T) {
 				key:        "non-exist-key",
 				value:      "value",
 				expiration: time.Minute,
-				client:     rdb,
+				backend:    redisBackend{client: rdb},
 			},
 			wantErr: ErrLockNotHeld,
 		},
@@ -392,3 +387,88 @@ func Test_e2e_Refresh(t *testing.T) {
 		})
 	}
 }
+
+func Test_e2e_Watchdog(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithWatchdog(time.Millisecond*100, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	l, err := client.TryLock(ctx, "watchdog_key", time.Second*3)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 300)
+	require.NoError(t, l.Context().Err())
+
+	// 锁被别人抢走了
+	require.NoError(t, rdb.Set(ctx, "watchdog_key", "other", time.Minute).Err())
+	select {
+	case err = <-l.Lost():
+		assert.ErrorIs(t, err, ErrLockLost)
+		assert.ErrorIs(t, err, ErrLockNotHeld)
+	case <-ctx.Done():
+		t.Fatal("没有感知到锁丢失")
+	}
+	assert.ErrorIs(t, context.Cause(l.Context()), ErrLockLost)
+	assert.Equal(t, ErrLockNotHeld, l.Unlock(ctx))
+	require.NoError(t, rdb.Del(ctx, "watchdog_key").Err())
+}
+
+func Test_e2e_FencingToken(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithoutWatchdog())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	defer rdb.Del(ctx, "fencing_key", fencingKey("fencing_key"))
+
+	l1, err := client.TryLock(ctx, "fencing_key", time.Minute)
+	require.NoError(t, err)
+	token := l1.FencingToken()
+	assert.True(t, token > 0)
+	// 续约不会改变 token
+	require.NoError(t, l1.Refresh(ctx))
+	assert.Equal(t, token, l1.FencingToken())
+	require.NoError(t, l1.Unlock(ctx))
+
+	// 换了持有者之后 token 递增
+	l2, err := client.Lock(ctx, "fencing_key", time.Minute, time.Second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, token+1, l2.FencingToken())
+	require.NoError(t, l2.Unlock(ctx))
+
+	l3, err := client.TryLockFair(ctx, "fencing_key", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, token+2, l3.FencingToken())
+	require.NoError(t, l3.Unlock(ctx))
+}
+
+func Test_e2e_SubSecondExpiration(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithoutWatchdog())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	defer rdb.Del(ctx, "sub_second_key", fencingKey("sub_second_key"))
+
+	l, err := client.TryLock(ctx, "sub_second_key", time.Millisecond*1500)
+	require.NoError(t, err)
+	ttl, err := l.TTL(ctx)
+	require.NoError(t, err)
+	assert.True(t, ttl > time.Second && ttl <= time.Millisecond*1500)
+	assert.True(t, time.Until(l.ValidUntil()) <= time.Millisecond*1500)
+
+	before := l.ValidUntil()
+	require.NoError(t, l.Extend(ctx, time.Millisecond*2500))
+	assert.True(t, l.ValidUntil().After(before))
+	ttl, err = l.TTL(ctx)
+	require.NoError(t, err)
+	assert.True(t, ttl > time.Second*2 && ttl <= time.Millisecond*2500)
+
+	require.NoError(t, l.Unlock(ctx))
+	_, err = l.TTL(ctx)
+	assert.Equal(t, ErrLockNotHeld, err)
+}
@@ -0,0 +1,164 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ErrInvalidLockOption 加锁的参数不合法，在访问 Redis 之前就会返回
+var ErrInvalidLockOption = errors.New("invalid lock option")
+
+const (
+	defaultLockExpiration    = time.Second * 30
+	defaultPerAttemptTimeout = time.Second
+	defaultLockRetryInitial  = time.Millisecond * 50
+	defaultLockRetryMax      = time.Second
+	defaultLockMaxRetries    = 10
+)
+
+// LockOption LockWithOptions 和 TryLockWithOptions 的参数
+type LockOption func(o *lockOptions)
+
+type lockOptions struct {
+	expiration time.Duration
+	timeout    time.Duration
+	retry      retry.Factory
+	// 锁的 value，也就是持有者的标识
+	valueGenerator func() string
+	// WithValue 和 WithValueGenerator 调用的次数
+	valueSet int
+	owner    Owner
+	// 只在 TryLockWithOptions 里面用来检查，WithRetry 对它没有意义
+	retrySet bool
+	// 没有设置 WithPerAttemptTimeout 的时候按照过期时间调整默认值
+	timeoutSet bool
+}
+
+// WithExpiration 锁的过期时间，默认 30 秒
+func WithExpiration(expiration time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.expiration = expiration
+	}
+}
+
+// WithRetry 加锁失败之后的重试策略，每次加锁都会用 factory 创建一个新的策略
+// 默认是从 50ms 开始的指数退避，最多重试 10 次；factory 为 nil 的时候只尝试一次。
+// 只能用于 LockWithOptions
+func WithRetry(factory retry.Factory) LockOption {
+	return func(o *lockOptions) {
+		o.retry = factory
+		o.retrySet = true
+	}
+}
+
+// WithPerAttemptTimeout 每一次加锁的超时时间，0 代表不设置超时
+// 默认是 1 秒，过期时间不到 1 秒的时候默认和过期时间一样。
+// 不能超过锁的过期时间，否则可能拿到一把已经过期的锁
+func WithPerAttemptTimeout(timeout time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.timeout = timeout
+		o.timeoutSet = true
+	}
+}
+
+// WithValue 使用指定的 value 加锁，默认是随机的 uuid
+// 同一个 value 对同一个 key 重复加锁会直接成功并续约，
+// 可以用来在进程重启之后找回自己的锁，所以 value 一定要全局唯一。
+// 不能和 WithValueGenerator 一起使用
+func WithValue(value string) LockOption {
+	return WithValueGenerator(func() string {
+		return value
+	})
+}
+
+// WithValueGenerator 每次加锁的时候调用 generator 生成 value，默认是随机的 uuid
+// 生成的 value 要全局唯一，见 WithValue。不能和 WithValue 一起使用
+func WithValueGenerator(generator func() string) LockOption {
+	return func(o *lockOptions) {
+		o.valueGenerator = generator
+		o.valueSet++
+	}
+}
+
+// WithOwnerMetadata 这一把锁的持有者信息，Inspect 和 ListLocks 的时候可以看到
+// 为空的字段使用 Client 的默认值，也就是本机的 hostname、pid 以及 WithOwnerLabel 设置的标签，
+// AcquiredAt 会被忽略，总是使用加锁的时间
+func WithOwnerMetadata(owner Owner) LockOption {
+	return func(o *lockOptions) {
+		o.owner = mergeOwner(o.owner, owner)
+	}
+}
+
+// mergeOwner owner 里面为空的字段使用 base 的，AcquiredAt 会被忽略
+func mergeOwner(base Owner, owner Owner) Owner {
+	if owner.Hostname != "" {
+		base.Hostname = owner.Hostname
+	}
+	if owner.PID != 0 {
+		base.PID = owner.PID
+	}
+	if owner.Label != "" {
+		base.Label = owner.Label
+	}
+	return base
+}
+
+// LockWithOptions 加锁，加锁失败时按照 WithRetry 重试
+// 不合法的参数返回 ErrInvalidLockOption
+func (c *Client) LockWithOptions(ctx context.Context, key string, opts ...LockOption) (*Lock, error) {
+	o, err := c.lockOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.acquire(ctx, key, o)
+}
+
+// TryLockWithOptions 尝试加锁，锁被别人持有的时候返回 ErrFailedToPreemptLock
+// 不能使用 WithRetry，不合法的参数返回 ErrInvalidLockOption
+func (c *Client) TryLockWithOptions(ctx context.Context, key string, opts ...LockOption) (*Lock, error) {
+	o, err := c.lockOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.retrySet {
+		return nil, fmt.Errorf("%w: TryLock 不会重试，不能使用 WithRetry", ErrInvalidLockOption)
+	}
+	return c.tryAcquire(ctx, key, o)
+}
+
+func (c *Client) lockOptions(opts []LockOption) (lockOptions, error) {
+	o := lockOptions{
+		expiration:     defaultLockExpiration,
+		timeout:        defaultPerAttemptTimeout,
+		retry:          retry.ExponentialBackoff(defaultLockRetryInitial, defaultLockRetryMax, defaultLockMaxRetries),
+		valueGenerator: newLockValue,
+		owner:          c.owner,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.timeoutSet {
+		o.timeout = min(o.timeout, o.expiration)
+	}
+	switch {
+	case o.expiration < time.Millisecond:
+		return o, fmt.Errorf("%w: 过期时间至少是 1 毫秒", ErrInvalidLockOption)
+	case o.timeout < 0:
+		return o, fmt.Errorf("%w: 超时时间不能小于 0", ErrInvalidLockOption)
+	case o.timeout > o.expiration:
+		return o, fmt.Errorf("%w: 超时时间 %s 超过了过期时间 %s", ErrInvalidLockOption, o.timeout, o.expiration)
+	case o.valueSet > 1:
+		return o, fmt.Errorf("%w: WithValue 和 WithValueGenerator 只能用一个", ErrInvalidLockOption)
+	case o.valueGenerator == nil:
+		return o, fmt.Errorf("%w: value 生成器不能为 nil", ErrInvalidLockOption)
+	}
+	return o, nil
+}
+
+func newLockValue() string {
+	return uuid.New().String()
+}
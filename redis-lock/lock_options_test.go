@@ -0,0 +1,168 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_LockWithOptions_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []LockOption
+	}{
+		{
+			name: "zero expiration",
+			opts: []LockOption{WithExpiration(0)},
+		},
+		{
+			name: "sub-millisecond expiration",
+			opts: []LockOption{WithExpiration(time.Microsecond)},
+		},
+		{
+			name: "negative timeout",
+			opts: []LockOption{WithPerAttemptTimeout(-time.Second)},
+		},
+		{
+			name: "timeout longer than expiration",
+			opts: []LockOption{WithExpiration(time.Second), WithPerAttemptTimeout(time.Second * 2)},
+		},
+		{
+			name: "value and generator",
+			opts: []LockOption{WithValue("value1"), WithValueGenerator(newLockValue)},
+		},
+		{
+			name: "nil generator",
+			opts: []LockOption{WithValueGenerator(nil)},
+		},
+		{
+			name: "empty value",
+			opts: []LockOption{WithValue("")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			// 参数不合法的时候不会访问 Redis
+			client := NewClient(redismock.NewMockCmdable(ctrl))
+			_, err := client.LockWithOptions(context.Background(), "key1", tc.opts...)
+			assert.ErrorIs(t, err, ErrInvalidLockOption)
+			_, err = client.TryLockWithOptions(context.Background(), "key1", tc.opts...)
+			assert.ErrorIs(t, err, ErrInvalidLockOption)
+		})
+	}
+}
+
+func TestClient_TryLockWithOptions_Retry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := NewClient(redismock.NewMockCmdable(ctrl))
+	_, err := client.TryLockWithOptions(context.Background(), "key1",
+		WithRetry(retry.FixedInterval(time.Millisecond, 3)))
+	assert.ErrorIs(t, err, ErrInvalidLockOption)
+}
+
+func TestClient_LockWithOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	failed := redis.NewCmd(context.Background())
+	failed.SetVal(int64(0))
+	success := redis.NewCmd(context.Background())
+	success.SetVal(int64(5))
+	args := []any{"value1", int64(5000),
+		ownerFieldHostname, "host1", ownerFieldPID, 42,
+		ownerFieldAcquiredAt, gomock.Any(), ownerFieldLabel, "job"}
+	keys := []string{"key1", "{key1}:owner", "{key1}:fencing"}
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), keys, args...).Times(2).Return(failed),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), keys, args...).Return(success),
+	)
+	client := NewClient(cmd, WithOwnerLabel("default"), WithoutWatchdog())
+	l, err := client.LockWithOptions(context.Background(), "key1",
+		WithExpiration(time.Second*5),
+		WithPerAttemptTimeout(time.Millisecond*100),
+		WithRetry(retry.FixedInterval(time.Millisecond, 3)),
+		WithValue("value1"),
+		WithOwnerMetadata(Owner{Hostname: "host1", PID: 42, Label: "job"}))
+	require.NoError(t, err)
+	assert.Equal(t, "value1", l.value)
+	assert.Equal(t, time.Second*5, l.expiration)
+	assert.Equal(t, int64(5), l.FencingToken())
+}
+
+func TestClient_LockWithOptions_Defaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(1))
+	var deadline time.Time
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), lockArgs(int64(30000))...).
+		DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+			deadline, _ = ctx.Deadline()
+			return res
+		})
+	client := NewClient(cmd, WithoutWatchdog())
+	l, err := client.TryLockWithOptions(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultLockExpiration, l.expiration)
+	assert.NotEmpty(t, l.value)
+	// 每一次加锁都有超时时间
+	assert.WithinDuration(t, time.Now().Add(defaultPerAttemptTimeout), deadline, time.Millisecond*100)
+}
+
+func TestClient_LockWithOptions_ShortExpiration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(1))
+	var deadline time.Time
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), lockArgs(int64(500))...).
+		DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+			deadline, _ = ctx.Deadline()
+			return res
+		})
+	client := NewClient(cmd, WithoutWatchdog())
+	// 只设置了过期时间，默认的超时时间不能让参数变得不合法
+	l, err := client.LockWithOptions(context.Background(), "key1", WithExpiration(time.Millisecond*500))
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*500, l.expiration)
+	// 超时时间缩短到过期时间
+	assert.WithinDuration(t, time.Now().Add(time.Millisecond*500), deadline, time.Millisecond*100)
+}
+
+func TestClient_LockWithOptions_ValueGenerator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	cnt := 0
+	var vals []any
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Times(3).
+		DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+			vals = append(vals, args[0])
+			res := redis.NewCmd(context.Background())
+			res.SetVal(int64(len(vals) / 3))
+			return res
+		})
+	client := NewClient(cmd, WithoutWatchdog())
+	l, err := client.LockWithOptions(context.Background(), "key1",
+		WithRetry(retry.FixedInterval(time.Millisecond, 3)),
+		WithValueGenerator(func() string {
+			cnt++
+			return "gen-1"
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, "gen-1", l.value)
+	// 重试的时候不会重新生成 value
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, []any{"gen-1", "gen-1", "gen-1"}, vals)
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"testing"
 	"time"
@@ -19,7 +21,7 @@ func TestClient_Lock(t *testing.T) {
 		key        string
 		expiration time.Duration
 		timeout    time.Duration
-		retry      RetryStrategy
+		retry      retry.Factory
 		wantErr    error
 		wantLock   *Lock
 	}{
@@ -30,8 +32,8 @@ func TestClient_Lock(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetErr(context.DeadlineExceeded)
-				cmd.EXPECT().Eval(gomock.Any(),
-					luaLock, []string{"lock-key1"}, gomock.Any()).
+				cmd.EXPECT().EvalSha(gomock.Any(),
+					luaLock.Hash(), []string{"lock-key1", "{lock-key1}:owner", "{lock-key1}:fencing"}, gomock.Any()).
 					Return(res)
 
 				return cmd
@@ -51,8 +53,8 @@ func TestClient_Lock(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetVal(int64(1))
-				cmd.EXPECT().Eval(gomock.Any(),
-					luaLock, []string{"lock-key2"}, gomock.Any()).
+				cmd.EXPECT().EvalSha(gomock.Any(),
+					luaLock.Hash(), []string{"lock-key2", "{lock-key2}:owner", "{lock-key2}:fencing"}, gomock.Any()).
 					Return(res)
 
 				return cmd
@@ -64,8 +66,9 @@ func TestClient_Lock(t *testing.T) {
 			retry:      nil,
 			wantErr:    nil,
 			wantLock: &Lock{
-				key:        "lock-key2",
-				expiration: time.Minute,
+				key:          "lock-key2",
+				expiration:   time.Minute,
+				fencingToken: 1,
 			},
 		},
 		{
@@ -74,9 +77,9 @@ func TestClient_Lock(t *testing.T) {
 				cmd := redismock.NewMockCmdable(ctrl)
 
 				res := redis.NewCmd(context.Background())
-				res.SetVal(int64(2))
-				cmd.EXPECT().Eval(gomock.Any(),
-					luaLock, []string{"lock-key3"}, gomock.Any()).Times(4).Return(res)
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(),
+					luaLock.Hash(), []string{"lock-key3", "{lock-key3}:owner", "{lock-key3}:fencing"}, gomock.Any()).Times(4).Return(res)
 
 				return cmd
 
@@ -84,12 +87,9 @@ func TestClient_Lock(t *testing.T) {
 			key:        "lock-key3",
 			expiration: time.Minute,
 			timeout:    time.Second * 5,
-			retry: &FixedIntervalRetryStrategy{
-				MaxCnt:   3,
-				Interval: time.Second,
-			},
-			wantErr:  fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
-			wantLock: nil,
+			retry:      retry.FixedInterval(time.Second, 3),
+			wantErr:    fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+			wantLock:   nil,
 		},
 		{
 			name: "retry and success",
@@ -97,14 +97,14 @@ func TestClient_Lock(t *testing.T) {
 				cmd := redismock.NewMockCmdable(ctrl)
 
 				res := redis.NewCmd(context.Background())
-				res.SetVal(int64(2))
-				cmd.EXPECT().Eval(gomock.Any(),
-					luaLock, []string{"lock-key4"}, gomock.Any()).Times(2).Return(res)
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(),
+					luaLock.Hash(), []string{"lock-key4", "{lock-key4}:owner", "{lock-key4}:fencing"}, gomock.Any()).Times(2).Return(res)
 				// 重试后成功
 				res2 := redis.NewCmd(context.Background())
-				res2.SetVal(int64(1))
-				cmd.EXPECT().Eval(gomock.Any(),
-					luaLock, []string{"lock-key4"}, gomock.Any()).Return(res2)
+				res2.SetVal(int64(3))
+				cmd.EXPECT().EvalSha(gomock.Any(),
+					luaLock.Hash(), []string{"lock-key4", "{lock-key4}:owner", "{lock-key4}:fencing"}, gomock.Any()).Return(res2)
 
 				return cmd
 
@@ -112,14 +112,12 @@ func TestClient_Lock(t *testing.T) {
 			key:        "lock-key4",
 			expiration: time.Minute,
 			timeout:    time.Second * 5,
-			retry: &FixedIntervalRetryStrategy{
-				MaxCnt:   3,
-				Interval: time.Second,
-			},
-			wantErr: nil,
+			retry:      retry.FixedInterval(time.Second, 3),
+			wantErr:    nil,
 			wantLock: &Lock{
-				key:        "lock-key4",
-				expiration: time.Minute,
+				key:          "lock-key4",
+				expiration:   time.Minute,
+				fencingToken: 3,
 			},
 		},
 	}
@@ -127,7 +125,7 @@ func TestClient_Lock(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
-			client := NewClient(tc.mock(ctrl))
+			client := NewClient(tc.mock(ctrl), WithoutWatchdog())
 
 			lock, err := client.Lock(context.Background(), tc.key, tc.expiration, tc.timeout, tc.retry)
 
@@ -137,12 +135,20 @@ func TestClient_Lock(t *testing.T) {
 			}
 			assert.Equal(t, tc.wantLock.key, lock.key)
 			assert.Equal(t, tc.wantLock.expiration, lock.expiration)
+			assert.Equal(t, tc.wantLock.fencingToken, lock.FencingToken())
 			assert.NotEmpty(t, lock.value)
-			assert.NotNil(t, lock.client)
+			assert.NotNil(t, lock.backend)
 		})
 	}
 }
 
+// lockArgs luaLock 的参数，持有者信息只检查字段名
+func lockArgs(expiration int64) []any {
+	return []any{gomock.Any(), expiration,
+		ownerFieldHostname, gomock.Any(), ownerFieldPID, gomock.Any(),
+		ownerFieldAcquiredAt, gomock.Any(), ownerFieldLabel, gomock.Any()}
+}
+
 func TestClient_TryLock(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -152,13 +158,13 @@ func TestClient_TryLock(t *testing.T) {
 		wantLock *Lock
 	}{
 		{
-			name: "setnx error",
+			name: "eval error",
 			mockCmd: func(ctrl *gomock.Controller) redis.Cmdable {
 				cmd := redismock.NewMockCmdable(ctrl)
-				// 这个用来模拟 setnx 命令的返回值
-				res := redis.NewBoolResult(false, context.DeadlineExceeded)
-				cmd.EXPECT().SetNX(context.Background(),
-					"key1", gomock.Any(), time.Second*10).
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(context.Background(), luaLock.Hash(),
+					[]string{"key1", "{key1}:owner", "{key1}:fencing"}, lockArgs(int64(10000))...).
 					Return(res)
 				return cmd
 			},
@@ -169,10 +175,10 @@ func TestClient_TryLock(t *testing.T) {
 			name: "preempt lock failed",
 			mockCmd: func(ctrl *gomock.Controller) redis.Cmdable {
 				cmd := redismock.NewMockCmdable(ctrl)
-				// 这个用来模拟 setnx 命令的返回值
-				res := redis.NewBoolResult(false, nil)
-				cmd.EXPECT().SetNX(context.Background(),
-					"key1", gomock.Any(), time.Second*10).
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(context.Background(), luaLock.Hash(),
+					[]string{"key1", "{key1}:owner", "{key1}:fencing"}, lockArgs(int64(10000))...).
 					Return(res)
 				return cmd
 			},
@@ -183,18 +189,20 @@ func TestClient_TryLock(t *testing.T) {
 			name: "success",
 			mockCmd: func(ctrl *gomock.Controller) redis.Cmdable {
 				cmd := redismock.NewMockCmdable(ctrl)
-				// 这个用来模拟 setnx 命令的返回值
-				res := redis.NewBoolResult(true, nil)
-				cmd.EXPECT().SetNX(context.Background(),
-					"key1", gomock.Any(), time.Second*10).
+				// 加锁成功返回 fencing token
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(7))
+				cmd.EXPECT().EvalSha(context.Background(), luaLock.Hash(),
+					[]string{"key1", "{key1}:owner", "{key1}:fencing"}, lockArgs(int64(10000))...).
 					Return(res)
 				return cmd
 			},
 			key:     "key1",
 			wantErr: nil,
 			wantLock: &Lock{
-				key:        "key1",
-				expiration: time.Second * 10,
+				key:          "key1",
+				expiration:   time.Second * 10,
+				fencingToken: 7,
 			},
 		},
 	}
@@ -203,21 +211,41 @@ func TestClient_TryLock(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
-			client := NewClient(tc.mockCmd(ctrl))
+			client := NewClient(tc.mockCmd(ctrl), WithoutWatchdog())
 
+			start := time.Now()
 			lock, err := client.TryLock(context.Background(), tc.key, time.Second*10)
 
 			assert.Equal(t, tc.wantErr, err)
 			if err != nil {
 				return
 			}
+			// 过期时间从发出加锁请求的时候开始算
+			assert.False(t, lock.ValidUntil().Before(start.Add(time.Second*10)))
+			assert.False(t, lock.ValidUntil().After(time.Now().Add(time.Second*10)))
 			assert.Equal(t, tc.wantLock.key, lock.key)
 			assert.Equal(t, tc.wantLock.expiration, lock.expiration)
+			assert.Equal(t, tc.wantLock.fencingToken, lock.FencingToken())
 			assert.NotEmpty(t, lock.value)
 		})
 	}
 }
 
+func TestClient_TryLock_WithoutFencing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(1))
+	// 关闭了 fencing 的时候不会访问计数器
+	cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(),
+		[]string{"key1", "{key1}:owner"}, lockArgs(int64(10000))...).Return(res)
+	client := NewClient(cmd, WithoutFencing(), WithoutWatchdog())
+	lock, err := client.TryLock(context.Background(), "key1", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lock.FencingToken())
+}
+
 func TestClient_Unlock(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -233,8 +261,8 @@ func TestClient_Unlock(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetErr(context.DeadlineExceeded)
-				cmd.EXPECT().Eval(context.Background(),
-					luaUnlock, []string{"key1"}, []any{"value1"}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", 0}).
 					Return(res)
 
 				return cmd
@@ -251,8 +279,8 @@ func TestClient_Unlock(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetVal(int64(0))
-				cmd.EXPECT().Eval(context.Background(),
-					luaUnlock, []string{"key1"}, []any{"value1"}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", 0}).
 					Return(res)
 
 				return cmd
@@ -269,8 +297,8 @@ func TestClient_Unlock(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetVal(int64(1))
-				cmd.EXPECT().Eval(context.Background(),
-					luaUnlock, []string{"key1"}, []any{"value1"}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", 0}).
 					Return(res)
 
 				return cmd
@@ -286,9 +314,9 @@ func TestClient_Unlock(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			lock := &Lock{
-				key:    tc.key,
-				value:  tc.value,
-				client: tc.mock(ctrl),
+				key:     tc.key,
+				value:   tc.value,
+				backend: redisBackend{client: tc.mock(ctrl)},
 			}
 			err := lock.Unlock(context.Background())
 			assert.Equal(t, tc.wantErr, err)
@@ -312,8 +340,8 @@ func TestClient_Refresh(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetErr(context.DeadlineExceeded)
-				cmd.EXPECT().Eval(context.Background(),
-					luaRefresh, []string{"key1"}, []any{"value1", float64(60)}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", int64(60000)}).
 					Return(res)
 
 				return cmd
@@ -331,8 +359,8 @@ func TestClient_Refresh(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetVal(int64(0))
-				cmd.EXPECT().Eval(context.Background(),
-					luaRefresh, []string{"key1"}, []any{"value1", float64(60)}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", int64(60000)}).
 					Return(res)
 
 				return cmd
@@ -350,8 +378,8 @@ func TestClient_Refresh(t *testing.T) {
 
 				res := redis.NewCmd(context.Background())
 				res.SetVal(int64(1))
-				cmd.EXPECT().Eval(context.Background(),
-					luaRefresh, []string{"key1"}, []any{"value1", float64(60)}).
+				cmd.EXPECT().EvalSha(context.Background(),
+					luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, []any{"value1", int64(60000)}).
 					Return(res)
 
 				return cmd
@@ -370,7 +398,7 @@ func TestClient_Refresh(t *testing.T) {
 			lock := &Lock{
 				key:        tc.key,
 				value:      tc.value,
-				client:     tc.mock(ctrl),
+				backend:    redisBackend{client: tc.mock(ctrl)},
 				expiration: tc.expiration,
 			}
 			err := lock.Refresh(context.Background())
@@ -379,6 +407,66 @@ func TestClient_Refresh(t *testing.T) {
 	}
 }
 
+func TestLock_TTL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantTTL time.Duration
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(context.Background(), luaTTL.Hash(), []string{"key1"}, "value1").
+					Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(-2))
+				cmd.EXPECT().EvalSha(context.Background(), luaTTL.Hash(), []string{"key1"}, "value1").
+					Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1500))
+				cmd.EXPECT().EvalSha(context.Background(), luaTTL.Hash(), []string{"key1"}, "value1").
+					Return(res)
+				return cmd
+			},
+			wantTTL: time.Millisecond * 1500,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := &Lock{
+				key:     "key1",
+				value:   "value1",
+				backend: redisBackend{client: tc.mock(ctrl)},
+			}
+			ttl, err := lock.TTL(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantTTL, ttl)
+		})
+	}
+}
+
 func ExampleLock_Refresh() {
 	// 假设加锁成功，拿到了lock
 	var l *Lock
@@ -491,9 +579,7 @@ FOR:
 	close(stopChan)
 	close(errChan)
 
-	fmt.Println("Hello")
-	// Output:
-	// Hello
+	// 示例需要连接 Redis，所以只编译，不运行
 }
 
 func ExampleLock_AutoRefresh() {
@@ -506,7 +592,26 @@ func ExampleLock_AutoRefresh() {
 	}()
 	// 执行业务
 
-	fmt.Println("Hello")
-	// Output:
-	// Hello
+	// 示例需要连接 Redis，所以只编译，不运行
+}
+
+func ExampleLock_Context() {
+	var client *Client
+	l, err := client.TryLock(context.Background(), "key1", time.Second*10)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = l.Unlock(context.Background())
+	}()
+	// 看门狗在后台续约，锁丢失之后 ctx 会被取消，业务应该停下来
+	ctx := l.Context()
+	select {
+	case <-ctx.Done():
+		fmt.Println(context.Cause(ctx))
+	default:
+		// 执行业务
+	}
+
+	// 示例需要连接 Redis，所以只编译，不运行
 }
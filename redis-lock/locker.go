@@ -0,0 +1,23 @@
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"time"
+)
+
+// Locker 分布式锁的抽象，拿到的 *Lock 可以 Refresh 和 Unlock
+// Client 是基于 Redis 的实现，MemoryLocker 是进程内的实现，
+// 业务依赖 Locker，单机部署或者单元测试的时候可以换成 MemoryLocker
+type Locker interface {
+	// Lock 加锁，加锁失败时按照 factory 创建的重试策略重试，timeout 是每一次加锁的超时时间
+	Lock(ctx context.Context, key string,
+		expiration time.Duration, timeout time.Duration, factory retry.Factory) (*Lock, error)
+	// TryLock 尝试加锁，锁被别人持有的时候返回 ErrFailedToPreemptLock
+	TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error)
+}
+
+var (
+	_ Locker = (*Client)(nil)
+	_ Locker = (*MemoryLocker)(nil)
+)
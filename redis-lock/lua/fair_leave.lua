@@ -0,0 +1,6 @@
+-- 放弃排队
+-- KEYS[1] 等待队列
+-- KEYS[2] 等待者的超时时间
+-- ARGV[1] 等待者的标识
+redis.call('ZREM', KEYS[1], ARGV[1])
+return redis.call('ZREM', KEYS[2], ARGV[1])
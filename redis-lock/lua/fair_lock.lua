@@ -0,0 +1,63 @@
+-- 公平锁，按照到达的顺序加锁
+-- KEYS[1] 锁的 key
+-- KEYS[2] 等待队列，zset，score 是到达的时间
+-- KEYS[3] 等待者的超时时间，zset，score 是超时的时间戳，超时没有再来排队的等待者会被移出队列
+-- KEYS[4] 持有者信息的 hash
+-- KEYS[5] fencing token 的计数器，可以不传，不传的时候不生成 fencing token
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 锁的过期时间，单位毫秒，同时也是等待者的超时时间
+-- ARGV[3] 加锁失败的时候是否排队，1 代表排队
+-- ARGV[4...] 持有者信息，和 lock.lua 一样 field value 交替出现，和锁一起过期
+-- 加锁成功返回 fencing token，没有传 KEYS[5] 的时候返回 1；失败返回 0
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+-- 清理掉超时的等待者，比如崩溃了的进程，免得堵住后面所有人
+local dead = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now)
+for _, id in ipairs(dead) do
+    redis.call('ZREM', KEYS[2], id)
+    redis.call('ZREM', KEYS[3], id)
+end
+
+local owner = redis.call('GET', KEYS[1])
+if owner == ARGV[1] then
+    -- 上次加锁成功，重新设置过期时间，持有者信息保留第一次加锁的时候写入的
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    redis.call('PEXPIRE', KEYS[4], ARGV[2])
+    if not KEYS[5] then
+        return 1
+    end
+    -- 持有锁期间别人不会修改计数器，所以计数器的值就是上次拿到的 token
+    local token = redis.call('GET', KEYS[5])
+    if token == false then
+        return redis.call('INCR', KEYS[5])
+    end
+    return tonumber(token)
+end
+local head = redis.call('ZRANGE', KEYS[2], 0, 0)
+if owner == false and (head[1] == nil or head[1] == ARGV[1]) then
+    -- 锁空闲，并且轮到自己了
+    redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+    redis.call('ZREM', KEYS[2], ARGV[1])
+    redis.call('ZREM', KEYS[3], ARGV[1])
+    -- 先删掉，避免残留上一个持有者的字段
+    redis.call('DEL', KEYS[4])
+    if #ARGV > 3 then
+        redis.call('HSET', KEYS[4], unpack(ARGV, 4))
+        redis.call('PEXPIRE', KEYS[4], ARGV[2])
+    end
+    if not KEYS[5] then
+        return 1
+    end
+    return redis.call('INCR', KEYS[5])
+end
+if ARGV[3] ~= '1' then
+    return 0
+end
+-- 排队，已经在队列里面的话保持原来的位置，只更新超时时间
+local us = tonumber(t[1]) * 1000000 + tonumber(t[2])
+redis.call('ZADD', KEYS[2], 'NX', us, ARGV[1])
+redis.call('ZADD', KEYS[3], now + tonumber(ARGV[2]), ARGV[1])
+-- 队列本身也要设置过期时间，所有人都走了之后不会残留
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+return 0
@@ -0,0 +1,26 @@
+-- KEYS[1] 锁的 key
+-- KEYS[2] fencing token 的计数器
+-- KEYS[3] 持有者信息的 hash
+-- ARGV[1] 为 1 的时候发消息通知等待这把锁的人
+-- 不管持有者是谁都删除锁，返回值和 inspect.lua 一样，是删除之前的状态
+-- fencing token 的计数器不能删，否则之后拿到的 token 会变小
+-- 和 inspect.lua 一样，只释放 lock.lua 加的锁，免得误删业务自己的 key
+if redis.call('TYPE',KEYS[1]).ok ~= 'string' or redis.call('EXISTS',KEYS[3]) == 0 then
+    return nil
+end
+local token = redis.call('GET',KEYS[2])
+if token == false then
+    token = 0
+end
+local res = {
+    redis.call('GET',KEYS[1]),
+    redis.call('PTTL',KEYS[1]),
+    tonumber(token),
+    redis.call('HGETALL',KEYS[3]),
+}
+redis.call('DEL',KEYS[1],KEYS[3])
+if ARGV[1] == '1' then
+    -- 通知等待这把锁的人，频道名字要和 unlockChannel 保持一致
+    redis.call('PUBLISH', 'redis-lock:unlock:' .. KEYS[1], 1)
+end
+return res
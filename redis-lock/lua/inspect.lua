@@ -0,0 +1,19 @@
+-- KEYS[1] 锁的 key
+-- KEYS[2] fencing token 的计数器
+-- KEYS[3] 持有者信息的 hash
+-- 锁不存在，或者不是 lock.lua 加的锁（比如读写锁、公平锁、业务自己的 key）的时候返回 nil
+-- 否则返回 {持有者的标识, 剩余的过期时间（毫秒）, fencing token, 持有者信息}
+-- lock.lua 加锁的时候一定会写持有者信息，没有持有者信息的 string 不认为是锁
+if redis.call('TYPE',KEYS[1]).ok ~= 'string' or redis.call('EXISTS',KEYS[3]) == 0 then
+    return nil
+end
+local token = redis.call('GET',KEYS[2])
+if token == false then
+    token = 0
+end
+return {
+    redis.call('GET',KEYS[1]),
+    redis.call('PTTL',KEYS[1]),
+    tonumber(token),
+    redis.call('HGETALL',KEYS[3]),
+}
@@ -1,13 +1,46 @@
-local val = redis.call('GET', KEYS[1])
--- key存在，redis返回nil回复，对应的lua类型取值为false
-if val == false then
-    -- 没有加锁, 成功返回 OK
-    redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
-    return 1
-elseif val == ARGV[1] then
-    -- 上次加锁成功，重新设置过期时间，设置成功返回1，失败返回0（这个发生的概率很小）
-    return redis.call('EXPIRE',KEYS[1],ARGV[2])
-else
-    -- 锁被人拿着
-    return 2
-end
\ No newline at end of file
+-- KEYS[1] 锁的 key
+-- KEYS[2] 持有者信息的 hash，可以不传
+-- KEYS[3] fencing token 的计数器，可以不传，不传的时候不生成 fencing token
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+-- ARGV[3...] 持有者信息，field value 交替出现，和锁一起过期
+-- 加锁成功返回 fencing token，没有传 KEYS[3] 的时候返回 1；失败返回 0
+local val = redis.call('GET', KEYS[1])
+-- key存在，redis返回nil回复，对应的lua类型取值为false
+if val == false then
+    -- 没有加锁, 成功返回 OK
+    redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+    if KEYS[2] then
+        -- 先删掉，避免残留上一个持有者的字段
+        redis.call('DEL', KEYS[2])
+        if #ARGV > 2 then
+            redis.call('HSET', KEYS[2], unpack(ARGV, 3))
+            redis.call('PEXPIRE', KEYS[2], ARGV[2])
+        end
+    end
+    if KEYS[3] then
+        -- 计数器不设置过期时间，保证换了持有者之后 token 依旧是递增的
+        return redis.call('INCR', KEYS[3])
+    end
+    return 1
+elseif val == ARGV[1] then
+    -- 上次加锁成功，重新设置过期时间，设置成功返回1，失败返回0（这个发生的概率很小）
+    local ok = redis.call('PEXPIRE',KEYS[1],ARGV[2])
+    if ok == 1 and KEYS[2] then
+        -- 持有者信息保留第一次加锁的时候写入的
+        redis.call('PEXPIRE', KEYS[2], ARGV[2])
+    end
+    if ok == 1 and KEYS[3] then
+        -- 持有锁期间别人不会修改计数器，所以计数器的值就是上次拿到的 token
+        local token = redis.call('GET', KEYS[3])
+        if token == false then
+            -- 计数器被人删掉了
+            return redis.call('INCR', KEYS[3])
+        end
+        return tonumber(token)
+    end
+    return ok
+else
+    -- 锁被人拿着，token 可能是任何正数，所以只能返回 0
+    return 0
+end
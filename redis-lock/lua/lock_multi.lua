@@ -0,0 +1,16 @@
+-- 一次性锁住多个 key，要么全部成功，要么全部失败
+-- KEYS 所有要锁住的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+for i = 1, #KEYS do
+    local val = redis.call('GET', KEYS[i])
+    if val ~= false and val ~= ARGV[1] then
+        -- 有一个 key 被别人拿着
+        return 0
+    end
+end
+-- 上次加锁成功了但是客户端超时的情况下，这里相当于续约
+for i = 1, #KEYS do
+    redis.call('SET', KEYS[i], ARGV[1], 'PX', ARGV[2])
+end
+return 1
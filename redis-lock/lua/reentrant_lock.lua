@@ -0,0 +1,19 @@
+-- 可重入锁用 hash 保存持有者和重入次数
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+local owner = redis.call('HGET', KEYS[1], 'owner')
+if owner == false then
+    -- 没有人持有锁
+    redis.call('HSET', KEYS[1], 'owner', ARGV[1], 'count', 1)
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    return 1
+elseif owner == ARGV[1] then
+    -- 自己持有锁，重入次数加一，并且重新设置过期时间
+    local cnt = redis.call('HINCRBY', KEYS[1], 'count', 1)
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    return cnt
+else
+    -- 锁被人拿着
+    return 0
+end
@@ -0,0 +1,10 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[1] then
+    -- 是自己的锁，整个 hash 一起续约，所有的重入都会被续上
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+else
+    -- 不是自己的锁，或者没有持有锁
+    return 0
+end
@@ -0,0 +1,14 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- 返回剩余的重入次数，返回 -1 代表不是自己的锁
+if redis.call('HGET', KEYS[1], 'owner') ~= ARGV[1] then
+    -- 不是自己的锁，或者没有持有锁
+    return -1
+end
+local cnt = redis.call('HINCRBY', KEYS[1], 'count', -1)
+if cnt <= 0 then
+    -- 重入次数减到 0 才真正释放锁
+    redis.call('DEL', KEYS[1])
+    return 0
+end
+return cnt
@@ -1,7 +1,14 @@
-if redis.call('GET',KEYS[1]) == ARGV[1] then
-    -- 是自己的锁
-    return redis.call('EXPIRE',KEYS[1],ARGV[2])
-else
-    -- 不是自己的锁，或者没有持有锁
-    return 0
-end
\ No newline at end of file
+-- KEYS[1] 锁的 key
+-- KEYS[2] 持有者信息的 hash，可以不传
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+if redis.call('GET',KEYS[1]) == ARGV[1] then
+    -- 是自己的锁
+    if KEYS[2] then
+        redis.call('PEXPIRE',KEYS[2],ARGV[2])
+    end
+    return redis.call('PEXPIRE',KEYS[1],ARGV[2])
+else
+    -- 不是自己的锁，或者没有持有锁
+    return 0
+end
@@ -0,0 +1,13 @@
+-- KEYS 所有锁住的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+for i = 1, #KEYS do
+    if redis.call('GET', KEYS[i]) ~= ARGV[1] then
+        -- 有一个 key 已经不是自己的了，整把锁都丢了
+        return 0
+    end
+end
+for i = 1, #KEYS do
+    redis.call('PEXPIRE', KEYS[i], ARGV[2])
+end
+return 1
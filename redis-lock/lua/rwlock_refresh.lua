@@ -0,0 +1,17 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的 field，r:<id> 或者 w:<id>
+-- ARGV[2] 过期时间，单位毫秒
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local old = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if old == nil or old <= now then
+    -- 不是自己的锁，或者已经过期了
+    return 0
+end
+local deadline = now + tonumber(ARGV[2])
+redis.call('HSET', KEYS[1], ARGV[1], deadline)
+-- 只续约自己，key 的过期时间不能比其他持有者更早
+if redis.call('PTTL', KEYS[1]) < deadline - now then
+    redis.call('PEXPIRE', KEYS[1], deadline - now)
+end
+return 1
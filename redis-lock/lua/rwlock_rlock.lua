@@ -0,0 +1,37 @@
+-- 读写锁用一个 hash 保存所有的持有者，field 是持有者，value 是持有者的过期时间（毫秒时间戳）
+-- r:<id> 读锁持有者，w:<id> 写锁持有者，p:<id> 正在等待的写者
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local data = redis.call('HGETALL', KEYS[1])
+local blocked = false
+local maxDeadline = 0
+for i = 1, #data, 2 do
+    local field, deadline = data[i], tonumber(data[i + 1])
+    if deadline <= now then
+        -- 清理掉已经过期的持有者，比如崩溃了的进程
+        redis.call('HDEL', KEYS[1], field)
+    else
+        local kind = string.sub(field, 1, 2)
+        if kind == 'w:' or kind == 'p:' then
+            -- 有写者持有锁，或者有写者在等待，新的读者都要让路，避免写者饿死
+            blocked = true
+        end
+        if deadline > maxDeadline then
+            maxDeadline = deadline
+        end
+    end
+end
+if blocked then
+    return 0
+end
+local deadline = now + tonumber(ARGV[2])
+redis.call('HSET', KEYS[1], 'r:' .. ARGV[1], deadline)
+if deadline > maxDeadline then
+    maxDeadline = deadline
+end
+-- 整个 key 的过期时间跟着最晚过期的持有者走
+redis.call('PEXPIRE', KEYS[1], maxDeadline - now)
+return 1
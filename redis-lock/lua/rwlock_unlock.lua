@@ -0,0 +1,16 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的 field，r:<id>、w:<id> 或者 p:<id>
+-- hash 里面的 field 全部删除之后，redis 会自动删除这个 key
+local deadline = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if deadline == nil then
+    -- 不是自己的锁，或者已经释放了
+    return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+if deadline <= now then
+    -- 已经过期了，锁早就不是自己的了
+    return 0
+end
+return 1
@@ -0,0 +1,42 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 过期时间，单位毫秒
+-- ARGV[3] 加锁失败的时候是否登记为等待的写者，1 代表登记
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local self = 'w:' .. ARGV[1]
+local data = redis.call('HGETALL', KEYS[1])
+local blocked = false
+local maxDeadline = 0
+for i = 1, #data, 2 do
+    local field, deadline = data[i], tonumber(data[i + 1])
+    if deadline <= now then
+        -- 清理掉已经过期的持有者，比如崩溃了的进程
+        redis.call('HDEL', KEYS[1], field)
+    else
+        local kind = string.sub(field, 1, 2)
+        if (kind == 'w:' and field ~= self) or kind == 'r:' then
+            -- 有别的写者或者读者持有锁
+            blocked = true
+        end
+        if deadline > maxDeadline then
+            maxDeadline = deadline
+        end
+    end
+end
+local deadline = now + tonumber(ARGV[2])
+if deadline > maxDeadline then
+    maxDeadline = deadline
+end
+if blocked then
+    if ARGV[3] == '1' then
+        -- 登记为等待的写者，之后新来的读者都加不了锁，等现有的读者释放之后写者就能拿到锁
+        redis.call('HSET', KEYS[1], 'p:' .. ARGV[1], deadline)
+        redis.call('PEXPIRE', KEYS[1], maxDeadline - now)
+    end
+    return 0
+end
+redis.call('HDEL', KEYS[1], 'p:' .. ARGV[1])
+redis.call('HSET', KEYS[1], self, deadline)
+redis.call('PEXPIRE', KEYS[1], maxDeadline - now)
+return 1
@@ -0,0 +1,28 @@
+-- 信号量用一个 zset 保存所有的许可，member 是 <持有者>:<序号>，score 是过期时间（毫秒时间戳）
+-- 一个持有者拿 n 个许可就占 n 个 member
+-- KEYS[1] 信号量的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 要获取的许可数量
+-- ARGV[3] 信号量的许可总数
+-- ARGV[4] 过期时间，单位毫秒
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+-- 清理掉已经过期的许可，比如崩溃了的进程，许可自动归还
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+
+local permits = tonumber(ARGV[2])
+local deadline = now + tonumber(ARGV[4])
+if redis.call('ZSCORE', KEYS[1], ARGV[1] .. ':1') == false then
+    if redis.call('ZCARD', KEYS[1]) + permits > tonumber(ARGV[3]) then
+        -- 剩余的许可不够
+        return 0
+    end
+end
+-- 上次获取成功了但是客户端超时的情况下，这里相当于续约
+for i = 1, permits do
+    redis.call('ZADD', KEYS[1], deadline, ARGV[1] .. ':' .. i)
+end
+-- 整个 key 的过期时间跟着最晚过期的许可走
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+redis.call('PEXPIRE', KEYS[1], tonumber(last[2]) - now)
+return 1
@@ -0,0 +1,18 @@
+-- KEYS[1] 信号量的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 持有的许可数量
+-- ARGV[3] 过期时间，单位毫秒
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZSCORE', KEYS[1], ARGV[1] .. ':1') == false then
+    -- 许可已经过期，可能已经被别人拿走了
+    return 0
+end
+local deadline = now + tonumber(ARGV[3])
+for i = 1, tonumber(ARGV[2]) do
+    redis.call('ZADD', KEYS[1], 'XX', deadline, ARGV[1] .. ':' .. i)
+end
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+redis.call('PEXPIRE', KEYS[1], tonumber(last[2]) - now)
+return 1
@@ -0,0 +1,12 @@
+-- KEYS[1] 信号量的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 持有的许可数量
+local members = {}
+for i = 1, tonumber(ARGV[2]) do
+    members[i] = ARGV[1] .. ':' .. i
+end
+if redis.call('ZREM', KEYS[1], unpack(members)) > 0 then
+    return 1
+end
+-- 许可已经过期了
+return 0
@@ -0,0 +1,26 @@
+-- KEYS[1] 锁的 key
+-- KEYS[2] 持有者信息的 hash
+-- ARGV[1] 原来的持有者的标识
+-- ARGV[2] 新的持有者的标识
+-- ARGV[3] 过期时间，单位毫秒
+-- ARGV[4...] 新的持有者信息，和 lock.lua 一样 field value 交替出现
+-- 转移成功返回 1，锁不是原来的持有者的返回 0
+local val = redis.call('GET',KEYS[1])
+if val == ARGV[1] then
+    -- 一条命令完成替换，中间没有锁不存在的时刻
+    redis.call('SET',KEYS[1],ARGV[2],'PX',ARGV[3])
+    -- 持有者信息换成新的持有者的，不残留原来的字段
+    redis.call('DEL',KEYS[2])
+    if #ARGV > 3 then
+        redis.call('HSET',KEYS[2],unpack(ARGV, 4))
+    end
+    redis.call('PEXPIRE',KEYS[2],ARGV[3])
+    return 1
+elseif val == ARGV[2] then
+    -- 已经转移过了也算成功，这样网络错误之后可以重试，持有者信息已经写过了
+    redis.call('SET',KEYS[1],ARGV[2],'PX',ARGV[3])
+    redis.call('PEXPIRE',KEYS[2],ARGV[3])
+    return 1
+else
+    return 0
+end
@@ -0,0 +1,8 @@
+-- KEYS[1] 锁的 key
+-- ARGV[1] 持有者的标识
+-- 是自己的锁返回剩余的过期时间，单位毫秒；不是自己的锁返回 -2
+if redis.call('GET',KEYS[1]) == ARGV[1] then
+    return redis.call('PTTL',KEYS[1])
+else
+    return -2
+end
@@ -1,10 +1,22 @@
--- 检查是不是自己的锁
--- 是，就删除锁
--- 以上两个步骤要做成原子操作，因此需要使用lua脚本来实现
-if redis.call('GET',KEYS[1]) == ARGV[1] then
-    -- 是自己的锁
-    return redis.call('del',KEYS[1])
-else
-    -- 不是自己的锁，或者没有持有锁
-    return 0
-end
\ No newline at end of file
+-- KEYS[1] 锁的 key
+-- KEYS[2] 持有者信息的 hash，可以不传
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 为 1 的时候发消息通知等待这把锁的人，见 Client.publishFlag
+-- 检查是不是自己的锁
+-- 是，就删除锁
+-- 以上两个步骤要做成原子操作，因此需要使用lua脚本来实现
+if redis.call('GET',KEYS[1]) == ARGV[1] then
+    -- 是自己的锁
+    local res = redis.call('del',KEYS[1])
+    if KEYS[2] then
+        redis.call('DEL',KEYS[2])
+    end
+    if ARGV[2] == '1' then
+        -- 通知等待这把锁的人，频道名字要和 unlockChannel 保持一致
+        redis.call('PUBLISH', 'redis-lock:unlock:' .. KEYS[1], 1)
+    end
+    return res
+else
+    -- 不是自己的锁，或者没有持有锁
+    return 0
+end
@@ -0,0 +1,19 @@
+-- KEYS 所有锁住的 key
+-- ARGV[1] 持有者的标识
+-- ARGV[2] 为 1 的时候发消息通知等待这把锁的人
+-- 只删除还是自己的 key，全部都是自己的才返回 1
+local cnt = 0
+for i = 1, #KEYS do
+    if redis.call('GET', KEYS[i]) == ARGV[1] then
+        redis.call('DEL', KEYS[i])
+        if ARGV[2] == '1' then
+            -- 通知等待这把锁的人，频道名字要和 unlockChannel 保持一致
+            redis.call('PUBLISH', 'redis-lock:unlock:' .. KEYS[i], 1)
+        end
+        cnt = cnt + 1
+    end
+end
+if cnt == #KEYS then
+    return 1
+end
+return 0
@@ -0,0 +1,184 @@
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// MemoryLocker 进程内的锁，语义和 Client 一样：锁会过期，只有持有者能续约和释放，
+// 加锁失败的时候按照 retry.Factory 创建的策略重试，每次加锁都会拿到递增的 fencing token。
+// 只能在同一个进程里面互斥，适合单机部署以及不想依赖 Redis 的单元测试
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryLockEntry
+	// 每个 key 的 fencing token 计数器，和 Redis 一样不会过期，开启了 WithoutMemoryFencing 的时候是空的
+	tokens   map[string]int64
+	fencing  bool
+	watchdog watchdogConfig
+	// 方便测试的时候控制时间
+	now func() time.Time
+}
+
+type memoryLockEntry struct {
+	value    string
+	deadline time.Time
+}
+
+// MemoryLockerOption MemoryLocker 的选项
+// Client 的通知、本地排队、Token 之类的选项对进程内的锁没有意义，所以单独定义
+type MemoryLockerOption func(m *MemoryLocker)
+
+// WithMemoryWatchdog 和 WithWatchdog 一样，设置看门狗的续约间隔以及每一次续约的超时时间
+func WithMemoryWatchdog(interval time.Duration, timeout time.Duration) MemoryLockerOption {
+	return func(m *MemoryLocker) {
+		m.watchdog = watchdogConfig{enabled: true, interval: interval, timeout: timeout}
+	}
+}
+
+// WithoutMemoryWatchdog 和 WithoutWatchdog 一样，关闭看门狗
+func WithoutMemoryWatchdog() MemoryLockerOption {
+	return func(m *MemoryLocker) {
+		m.watchdog = watchdogConfig{}
+	}
+}
+
+// WithoutMemoryFencing 和 WithoutFencing 一样，关闭 fencing token
+func WithoutMemoryFencing() MemoryLockerOption {
+	return func(m *MemoryLocker) {
+		m.fencing = false
+	}
+}
+
+// NewMemoryLocker 创建进程内的锁，和 NewClient 一样默认开启看门狗
+func NewMemoryLocker(opts ...MemoryLockerOption) *MemoryLocker {
+	m := &MemoryLocker{
+		locks:    make(map[string]memoryLockEntry),
+		tokens:   make(map[string]int64),
+		fencing:  true,
+		watchdog: watchdogConfig{enabled: true},
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MemoryLocker) Lock(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*Lock, error) {
+	val := uuid.New().String()
+	var token int64
+	var start time.Time
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		var err error
+		start = m.now()
+		token, err = m.lock(ctx, key, val, expiration)
+		return token > 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.newLock(key, val, expiration, token, start), nil
+}
+
+func (m *MemoryLocker) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	val := uuid.New().String()
+	start := m.now()
+	token, err := m.lock(ctx, key, val, expiration)
+	if err != nil {
+		return nil, err
+	}
+	if token <= 0 {
+		return nil, ErrFailedToPreemptLock
+	}
+	return m.newLock(key, val, expiration, token, start), nil
+}
+
+func (m *MemoryLocker) newLock(key string, val string, expiration time.Duration,
+	token int64, start time.Time) *Lock {
+	if !m.fencing {
+		token = 0
+	}
+	return m.watchdog.newLock(m, key, val, expiration, token, start)
+}
+
+// lock 和 lock.lua 一样，加锁成功返回 fencing token，开启了 WithoutMemoryFencing 的时候返回 1，失败返回 0
+func (m *MemoryLocker) lock(ctx context.Context, key string,
+	val string, expiration time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if ok && entry.value != val {
+		return 0, nil
+	}
+	m.locks[key] = memoryLockEntry{value: val, deadline: m.now().Add(expiration)}
+	if !m.fencing {
+		return 1, nil
+	}
+	if !ok {
+		m.tokens[key]++
+	}
+	return m.tokens[key], nil
+}
+
+func (m *MemoryLocker) refresh(ctx context.Context, key string,
+	value string, expiration time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if !ok || entry.value != value {
+		return 0, nil
+	}
+	m.locks[key] = memoryLockEntry{value: value, deadline: m.now().Add(expiration)}
+	return 1, nil
+}
+
+func (m *MemoryLocker) unlock(ctx context.Context, key string, value string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if !ok || entry.value != value {
+		return 0, nil
+	}
+	delete(m.locks, key)
+	return 1, nil
+}
+
+func (m *MemoryLocker) ttl(ctx context.Context, key string, value string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if !ok || entry.value != value {
+		return -2, nil
+	}
+	return entry.deadline.Sub(m.now()).Milliseconds(), nil
+}
+
+// get 返回还没有过期的锁，过期的锁会被顺手删掉
+// 调用方需要持有 m.mu
+func (m *MemoryLocker) get(key string) (memoryLockEntry, bool) {
+	entry, ok := m.locks[key]
+	if !ok {
+		return entry, false
+	}
+	if !m.now().Before(entry.deadline) {
+		delete(m.locks, key)
+		return entry, false
+	}
+	return entry, true
+}
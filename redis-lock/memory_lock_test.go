@@ -0,0 +1,158 @@
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryLocker_TryLock(t *testing.T) {
+	now := time.Now()
+	m := NewMemoryLocker(WithoutMemoryWatchdog())
+	m.now = func() time.Time {
+		return now
+	}
+	ctx := context.Background()
+
+	l1, err := m.TryLock(ctx, "key1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), l1.FencingToken())
+
+	// 别人持有
+	_, err = m.TryLock(ctx, "key1", time.Second)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	// 不同的 key 互不影响
+	l2, err := m.TryLock(ctx, "key2", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), l2.FencingToken())
+
+	// 过期之后别人可以拿到锁，token 递增
+	now = now.Add(time.Second)
+	l3, err := m.TryLock(ctx, "key1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), l3.FencingToken())
+
+	// 过期的锁不能续约，也不能释放别人的锁
+	assert.Equal(t, ErrLockNotHeld, l1.Refresh(ctx))
+	assert.Equal(t, ErrLockNotHeld, l1.Unlock(ctx))
+
+	ttl, err := l3.TTL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+	now = now.Add(time.Millisecond * 500)
+	require.NoError(t, l3.Refresh(ctx))
+	ttl, err = l3.TTL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+
+	require.NoError(t, l3.Unlock(ctx))
+	assert.Equal(t, ErrLockNotHeld, l3.Unlock(ctx))
+	_, err = l3.TTL(ctx)
+	assert.Equal(t, ErrLockNotHeld, err)
+
+	// 释放之后可以马上拿到锁
+	l4, err := m.TryLock(ctx, "key1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), l4.FencingToken())
+}
+
+func TestMemoryLocker_Lock(t *testing.T) {
+	m := NewMemoryLocker(WithoutMemoryWatchdog())
+	ctx := context.Background()
+	l1, err := m.TryLock(ctx, "key1", time.Millisecond*100)
+	require.NoError(t, err)
+
+	// 重试次数用完了锁还没有过期
+	_, err = m.Lock(ctx, "key1", time.Second, time.Second,
+		retry.FixedInterval(time.Millisecond*10, 2))
+	assert.ErrorIs(t, err, ErrFailedToPreemptLock)
+
+	// 重试期间锁过期了
+	l2, err := m.Lock(ctx, "key1", time.Second, time.Second,
+		retry.FixedInterval(time.Millisecond*50, 10))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), l2.FencingToken())
+	assert.Equal(t, ErrLockNotHeld, l1.Unlock(ctx))
+	require.NoError(t, l2.Unlock(ctx))
+
+	// ctx 过期
+	timeoutCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = m.Lock(timeoutCtx, "key1", time.Second, time.Second, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemoryLocker_WithoutFencing(t *testing.T) {
+	m := NewMemoryLocker(WithoutMemoryFencing(), WithoutMemoryWatchdog())
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		l, err := m.TryLock(ctx, "key1", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), l.FencingToken())
+		require.NoError(t, l.Unlock(ctx))
+	}
+	// 关闭之后不记录计数器
+	assert.Empty(t, m.tokens)
+}
+
+func TestNewMemoryLocker(t *testing.T) {
+	testCases := []struct {
+		name         string
+		opts         []MemoryLockerOption
+		wantWatchdog watchdogConfig
+		wantFencing  bool
+	}{
+		{
+			name:         "default",
+			wantWatchdog: watchdogConfig{enabled: true},
+			wantFencing:  true,
+		},
+		{
+			name:         "watchdog",
+			opts:         []MemoryLockerOption{WithMemoryWatchdog(time.Second, time.Millisecond*100)},
+			wantWatchdog: watchdogConfig{enabled: true, interval: time.Second, timeout: time.Millisecond * 100},
+			wantFencing:  true,
+		},
+		{
+			name: "without watchdog and fencing",
+			opts: []MemoryLockerOption{WithoutMemoryWatchdog(), WithoutMemoryFencing()},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMemoryLocker(tc.opts...)
+			assert.Equal(t, tc.wantWatchdog, m.watchdog)
+			assert.Equal(t, tc.wantFencing, m.fencing)
+		})
+	}
+}
+
+func TestMemoryLocker_Watchdog(t *testing.T) {
+	m := NewMemoryLocker()
+	ctx := context.Background()
+	l, err := m.TryLock(ctx, "key1", time.Millisecond*150)
+	require.NoError(t, err)
+	l.startWatchdog(0, 0)
+
+	// 看门狗一直续约，过期时间过了好几轮锁还在
+	time.Sleep(time.Millisecond * 400)
+	_, err = m.TryLock(ctx, "key1", time.Second)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	assert.NoError(t, l.Context().Err())
+
+	// 锁被强行删掉之后看门狗会发现
+	m.mu.Lock()
+	delete(m.locks, "key1")
+	m.mu.Unlock()
+	select {
+	case err = <-l.Lost():
+		assert.ErrorIs(t, err, ErrLockLost)
+	case <-time.After(time.Second):
+		t.Fatal("锁丢失了没有通知")
+	}
+	assert.ErrorIs(t, context.Cause(l.Context()), ErrLockNotHeld)
+}
@@ -0,0 +1,156 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"time"
+)
+
+//go:embed lua/lock_multi.lua
+var luaLockMultiSrc string
+var luaLockMulti = redis.NewScript(luaLockMultiSrc)
+
+//go:embed lua/refresh_multi.lua
+var luaRefreshMultiSrc string
+var luaRefreshMulti = redis.NewScript(luaRefreshMultiSrc)
+
+//go:embed lua/unlock_multi.lua
+var luaUnlockMultiSrc string
+var luaUnlockMulti = redis.NewScript(luaUnlockMultiSrc)
+
+var (
+	// ErrEmptyKeys 至少要锁住一个 key
+	ErrEmptyKeys = errors.New("empty keys")
+	// ErrCrossSlot Redis Cluster 下一个 lua 脚本里面的 key 必须落在同一个槽上，redis.Ring 下必须落在同一个分片上，
+	// 这些 key 要带上同样的 hash tag，比如 {account}:1 和 {account}:2，可以用 WithHashTag 生成
+	ErrCrossSlot = errors.New("keys must share the same hash tag in cluster mode")
+)
+
+// TryLockMulti 尝试一次性锁住多个 key，要么全部成功，要么一个都不锁
+func (c *Client) TryLockMulti(ctx context.Context, keys []string, expiration time.Duration) (*MultiKeyLock, error) {
+	keys, err := c.canonicalKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	val := uuid.New().String()
+	ok, err := c.lockMulti(ctx, keys, val, expiration)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFailedToPreemptLock
+	}
+	return newMultiKeyLock(c.client, keys, val, expiration, c.publishFlag()), nil
+}
+
+// LockMulti 一次性锁住多个 key，加锁失败时按照 factory 创建的重试策略重试
+// 所有的 key 在同一个 lua 脚本里面加锁，不会出现只锁住一部分的情况，
+// 也就不会因为加锁顺序不同而死锁。
+// timeout 是每一次加锁的超时时间
+func (c *Client) LockMulti(ctx context.Context, keys []string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*MultiKeyLock, error) {
+	keys, err := c.canonicalKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	val := uuid.New().String()
+	err = retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		return c.lockMulti(ctx, keys, val, expiration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newMultiKeyLock(c.client, keys, val, expiration, c.publishFlag()), nil
+}
+
+func (c *Client) lockMulti(ctx context.Context, keys []string,
+	val string, expiration time.Duration) (bool, error) {
+	res, err := luaLockMulti.Run(ctx, c.client, keys, val, expiration.Milliseconds()).Int64()
+	return res == 1, err
+}
+
+// canonicalKeys 去重并且排序，同样的一组 key 不管传入的顺序如何，加锁的顺序都是一样的
+// Redis Cluster 或者 redis.Ring 下还要检查所有的 key 都带有同样的 hash tag
+func (c *Client) canonicalKeys(keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, ErrEmptyKeys
+	}
+	res := slices.Clone(keys)
+	slices.Sort(res)
+	res = slices.Compact(res)
+	if isSharded(c.client) && len(res) > 1 {
+		tag := HashTag(res[0])
+		for _, key := range res {
+			if tag == "" || HashTag(key) != tag {
+				return nil, ErrCrossSlot
+			}
+		}
+	}
+	return res, nil
+}
+
+// MultiKeyLock 同时锁住多个 key 的锁，续约和释放都是对所有的 key 一起生效
+type MultiKeyLock struct {
+	client redis.Cmdable
+	// 排好序的 key
+	keys       []string
+	value      string
+	expiration time.Duration
+	// 见 Client.publishFlag
+	publish int
+}
+
+func newMultiKeyLock(client redis.Cmdable, keys []string, value string,
+	expiration time.Duration, publish int) *MultiKeyLock {
+	return &MultiKeyLock{
+		client:     client,
+		keys:       keys,
+		value:      value,
+		expiration: expiration,
+		publish:    publish,
+	}
+}
+
+// Keys 锁住的 key，已经去重并且排好序
+func (l *MultiKeyLock) Keys() []string {
+	return slices.Clone(l.keys)
+}
+
+// Refresh 续约所有的 key，只要有一个 key 已经不是自己的，就一个都不续约并返回 ErrLockNotHeld
+func (l *MultiKeyLock) Refresh(ctx context.Context) error {
+	res, err := luaRefreshMulti.Run(ctx, l.client, l.keys, l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock 释放所有还是自己的 key，有 key 已经不是自己的时候返回 ErrLockNotHeld
+func (l *MultiKeyLock) Unlock(ctx context.Context) error {
+	res, err := luaUnlockMulti.Run(ctx, l.client, l.keys, l.value, l.publish).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// isSharded 数据是不是分散在多个节点上
+func isSharded(client redis.Cmdable) bool {
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,51 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_LockMulti(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	defer rdb.Del(ctx, "account:1", "account:2", "account:3")
+
+	l1, err := client.TryLockMulti(ctx, []string{"account:2", "account:1"}, time.Minute)
+	require.NoError(t, err)
+
+	// 有一个 key 被别人锁住了，其它的 key 也不会被锁住
+	_, err = client.TryLockMulti(ctx, []string{"account:3", "account:2"}, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	cnt, err := rdb.Exists(ctx, "account:3").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+
+	require.NoError(t, l1.Refresh(ctx))
+	require.NoError(t, l1.Unlock(ctx))
+	cnt, err = rdb.Exists(ctx, "account:1", "account:2").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+
+	// 有一个 key 丢了，续约失败，释放的时候只删除还是自己的 key
+	l2, err := client.LockMulti(ctx, []string{"account:1", "account:3"}, time.Minute, time.Second, nil)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "account:3", "other", time.Minute).Err())
+	assert.Equal(t, ErrLockNotHeld, l2.Refresh(ctx))
+	assert.Equal(t, ErrLockNotHeld, l2.Unlock(ctx))
+	val, err := rdb.Get(ctx, "account:3").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "other", val)
+	cnt, err = rdb.Exists(ctx, "account:1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+}
@@ -0,0 +1,144 @@
+package redis_lock
+
+import (
+	"context"
+	"fmt"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_canonicalKeys(t *testing.T) {
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{})
+	defer cluster.Close()
+	ring := redis.NewRing(&redis.RingOptions{})
+	defer ring.Close()
+	testCases := []struct {
+		name     string
+		client   redis.Cmdable
+		keys     []string
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name:    "empty",
+			client:  redis.NewClient(&redis.Options{}),
+			wantErr: ErrEmptyKeys,
+		},
+		{
+			name:     "sort and dedup",
+			client:   redis.NewClient(&redis.Options{}),
+			keys:     []string{"b", "a", "c", "a"},
+			wantKeys: []string{"a", "b", "c"},
+		},
+		{
+			name:     "cluster same hash tag",
+			client:   cluster,
+			keys:     []string{"{account}:2", "{account}:1"},
+			wantKeys: []string{"{account}:1", "{account}:2"},
+		},
+		{
+			name:    "cluster different hash tag",
+			client:  cluster,
+			keys:    []string{"{account}:1", "{order}:1"},
+			wantErr: ErrCrossSlot,
+		},
+		{
+			name:    "cluster without hash tag",
+			client:  cluster,
+			keys:    []string{"account:1", "account:2"},
+			wantErr: ErrCrossSlot,
+		},
+		{
+			name:     "ring same hash tag",
+			client:   ring,
+			keys:     WithHashTag("account", "2", "1"),
+			wantKeys: []string{"{account}:1", "{account}:2"},
+		},
+		{
+			name:    "ring different hash tag",
+			client:  ring,
+			keys:    []string{"{account}:1", "{order}:1"},
+			wantErr: ErrCrossSlot,
+		},
+		{
+			name:     "cluster single key",
+			client:   cluster,
+			keys:     []string{"account:1"},
+			wantKeys: []string{"account:1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(tc.client)
+			keys, err := c.canonicalKeys(tc.keys)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantKeys, keys)
+		})
+	}
+}
+
+func TestClient_LockMulti(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr  error
+		wantKeys []string
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLockMulti.Hash(), []string{"key1", "key2"},
+					gomock.Any(), int64(60000)).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "retry and failed",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLockMulti.Hash(), []string{"key1", "key2"},
+					gomock.Any(), int64(60000)).Times(3).Return(res)
+				return cmd
+			},
+			wantErr: fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLockMulti.Hash(), []string{"key1", "key2"},
+					gomock.Any(), int64(60000)).Return(res)
+				return cmd
+			},
+			wantKeys: []string{"key1", "key2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			l, err := client.LockMulti(context.Background(), []string{"key2", "key1"}, time.Minute,
+				time.Second, retry.FixedInterval(time.Millisecond, 2))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantKeys, l.Keys())
+			assert.NotEmpty(t, l.value)
+		})
+	}
+}
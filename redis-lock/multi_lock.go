@@ -0,0 +1,191 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认的时钟漂移因子，参考 Redlock 算法的建议值
+	defaultDriftFactor = 0.01
+	// 额外预留的漂移时间，应对过期时间很短的情况
+	minDrift = time.Millisecond * 2
+)
+
+// MultiClient 在多个相互独立的 Redis 节点上加锁，也就是 Redlock 算法
+// 只有在过半数的节点上加锁成功，并且锁还在有效期内，才算加锁成功，
+// 这样单个节点发生故障或者主从切换，也不会把同一把锁交给两个持有者
+type MultiClient struct {
+	clients     []redis.Cmdable
+	driftFactor float64
+}
+
+// ErrNoClients 创建 MultiClient 的时候没有传入任何节点
+var ErrNoClients = errors.New("no redis clients")
+
+// NewMultiClient clients 之间必须相互独立，不能是同一个集群里面的主从节点
+// 没有节点的时候 quorum 是 1，永远加不上锁，所以直接返回 ErrNoClients
+func NewMultiClient(clients ...redis.Cmdable) (*MultiClient, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoClients
+	}
+	return &MultiClient{
+		clients:     clients,
+		driftFactor: defaultDriftFactor,
+	}, nil
+}
+
+// quorum 过半数
+func (m *MultiClient) quorum() int {
+	return len(m.clients)/2 + 1
+}
+
+// TryLock 尝试在所有节点上加锁
+// timeout 是每个节点加锁的超时时间，应该远小于 expiration，
+// 避免在一个挂掉的节点上等待太久
+func (m *MultiClient) TryLock(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration) (*MultiLock, error) {
+	val := uuid.New().String()
+	lock, err := m.tryLock(ctx, key, val, expiration, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, ErrFailedToPreemptLock
+	}
+	return lock, nil
+}
+
+// Lock 加锁，没能在过半数的节点上加锁成功时按照 factory 创建的重试策略重试
+func (m *MultiClient) Lock(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*MultiLock, error) {
+	val := uuid.New().String()
+	var lock *MultiLock
+	// 超时控制交给每个节点自己，这里的 timeout 不生效
+	err := retryLock(ctx, 0, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		var err error
+		lock, err = m.tryLock(ctx, key, val, expiration, timeout)
+		return lock != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// tryLock 加锁失败返回 nil
+// 单个节点的错误不会作为 error 返回，只有 ctx 本身出错的时候才返回 error
+func (m *MultiClient) tryLock(ctx context.Context, key string, val string,
+	expiration time.Duration, timeout time.Duration) (*MultiLock, error) {
+	start := time.Now()
+	success, _ := m.eval(ctx, timeout, luaLock, key, val, expiration.Milliseconds())
+	validUntil := m.validUntil(start, expiration)
+	if success >= m.quorum() && time.Now().Before(validUntil) {
+		return &MultiLock{
+			client:     m,
+			key:        key,
+			value:      val,
+			expiration: expiration,
+			timeout:    timeout,
+			validUntil: validUntil,
+		}, nil
+	}
+	// 加锁失败，已经加上的节点要释放掉，不然要等到过期别人才能加锁
+	// ctx 可能已经过期了，所以这里用一个新的 context
+	cleanupCtx, cancel := cleanupContext(timeout)
+	_, _ = m.eval(cleanupCtx, timeout, luaUnlock, key, val)
+	cancel()
+	return nil, ctx.Err()
+}
+
+// validUntil 锁的有效期要扣掉时钟漂移
+func (m *MultiClient) validUntil(start time.Time, expiration time.Duration) time.Time {
+	drift := time.Duration(float64(expiration)*m.driftFactor) + minDrift
+	return start.Add(expiration - drift)
+}
+
+// eval 并发地在所有节点上执行脚本，返回执行结果为 1 的节点数以及各个节点的错误
+func (m *MultiClient) eval(ctx context.Context, timeout time.Duration,
+	script *redis.Script, key string, args ...any) (int, error) {
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		success int
+		errs    []error
+	)
+	for _, client := range m.clients {
+		wg.Add(1)
+		go func(client redis.Cmdable) {
+			defer wg.Done()
+			// timeout <= 0 的时候不设置单个节点的超时，只受 ctx 控制
+			ctx2, cancel := ctx, context.CancelFunc(func() {})
+			if timeout > 0 {
+				ctx2, cancel = context.WithTimeout(ctx, timeout)
+			}
+			res, err := script.Run(ctx2, client, []string{key}, args...).Int64()
+			cancel()
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if res == 1 {
+				success++
+			}
+		}(client)
+	}
+	wg.Wait()
+	return success, errors.Join(errs...)
+}
+
+// MultiLock 在多个节点上持有的锁
+type MultiLock struct {
+	client *MultiClient
+	// key + value 才是锁的唯一标识
+	key        string
+	value      string
+	expiration time.Duration
+	// 每个节点的超时时间
+	timeout time.Duration
+	// 扣掉加锁耗时和时钟漂移之后，锁的有效期
+	validUntil time.Time
+}
+
+// ValidUntil 在这个时间之前可以认为自己一定持有锁
+func (l *MultiLock) ValidUntil() time.Time {
+	return l.validUntil
+}
+
+// Refresh 在所有节点上续约，过半数的节点续约成功才算成功
+func (l *MultiLock) Refresh(ctx context.Context) error {
+	start := time.Now()
+	success, err := l.client.eval(ctx, l.timeout, luaRefresh, l.key, l.value, l.expiration.Milliseconds())
+	validUntil := l.client.validUntil(start, l.expiration)
+	if success >= l.client.quorum() && time.Now().Before(validUntil) {
+		l.validUntil = validUntil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrLockNotHeld
+}
+
+// Unlock 在所有节点上释放锁
+// 只要还有过半数的节点持有锁就算释放成功，否则说明锁在此之前就已经丢了
+func (l *MultiLock) Unlock(ctx context.Context) error {
+	success, err := l.client.eval(ctx, l.timeout, luaUnlock, l.key, l.value)
+	if success >= l.client.quorum() {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrLockNotHeld
+}
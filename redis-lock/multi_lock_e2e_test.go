@@ -0,0 +1,68 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_MultiLock(t *testing.T) {
+	// 用同一个 Redis 的不同 DB 模拟三个相互独立的节点
+	nodes := make([]*redis.Client, 0, 3)
+	clients := make([]redis.Cmdable, 0, 3)
+	for i := 0; i < 3; i++ {
+		rdb := redis.NewClient(&redis.Options{
+			Addr: "localhost:6379",
+			DB:   i + 1,
+		})
+		nodes = append(nodes, rdb)
+		clients = append(clients, rdb)
+	}
+	client, err := NewMultiClient(clients...)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "multi-key1"
+
+	// 有一个节点被别人占了，依旧可以在过半数的节点上加锁成功
+	require.NoError(t, nodes[0].Set(ctx, key, "other", time.Minute).Err())
+	lock, err := client.TryLock(ctx, key, time.Minute, time.Second)
+	require.NoError(t, err)
+	for i, node := range nodes[1:] {
+		val, err := node.Get(ctx, key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, lock.value, val, "node %d", i+1)
+	}
+	require.NoError(t, lock.Refresh(ctx))
+
+	// 别人拿不到锁，并且加锁失败之后不会残留
+	_, err = client.TryLock(ctx, key, time.Minute, time.Second)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	val, err := nodes[0].Get(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "other", val)
+
+	require.NoError(t, lock.Unlock(ctx))
+	for _, node := range nodes[1:] {
+		exists, err := node.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), exists)
+	}
+
+	// 两个节点被别人占了，加锁失败，已经加上的节点要回滚
+	require.NoError(t, nodes[1].Set(ctx, key, "other", time.Minute).Err())
+	_, err = client.TryLock(ctx, key, time.Minute, time.Second)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	exists, err := nodes[2].Exists(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+
+	for _, node := range nodes[:2] {
+		require.NoError(t, node.Del(ctx, key).Err())
+	}
+}
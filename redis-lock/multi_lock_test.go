@@ -0,0 +1,247 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// mockNode 模拟一个 Redis 节点，script 的执行结果是 res 或者 err
+func mockNode(ctrl *gomock.Controller, script *redis.Script, res int64, err error, times int) *redismock.MockCmdable {
+	cmd := redismock.NewMockCmdable(ctrl)
+	expectEval(cmd, script, res, err, times)
+	return cmd
+}
+
+func expectEval(cmd *redismock.MockCmdable, script *redis.Script, res int64, err error, times int) {
+	r := redis.NewCmd(context.Background())
+	if err != nil {
+		r.SetErr(err)
+	} else {
+		r.SetVal(res)
+	}
+	cmd.EXPECT().EvalSha(gomock.Any(), script.Hash(), []string{"key1"}, gomock.Any()).Times(times).Return(r)
+}
+
+func TestMultiClient_TryLock(t *testing.T) {
+	nodeErr := errors.New("node down")
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) []redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "all nodes locked",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaLock, 1, nil, 1),
+					mockNode(ctrl, luaLock, 1, nil, 1),
+					mockNode(ctrl, luaLock, 1, nil, 1),
+				}
+			},
+		},
+		{
+			name: "majority locked",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaLock, 1, nil, 1),
+					mockNode(ctrl, luaLock, 0, nodeErr, 1),
+					mockNode(ctrl, luaLock, 1, nil, 1),
+				}
+			},
+		},
+		{
+			name: "minority locked and rollback",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				n1 := mockNode(ctrl, luaLock, 1, nil, 1)
+				expectEval(n1, luaUnlock, 1, nil, 1)
+				n2 := mockNode(ctrl, luaLock, 2, nil, 1)
+				expectEval(n2, luaUnlock, 0, nil, 1)
+				n3 := mockNode(ctrl, luaLock, 0, nodeErr, 1)
+				expectEval(n3, luaUnlock, 0, nodeErr, 1)
+				return []redis.Cmdable{n1, n2, n3}
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client, err := NewMultiClient(tc.mock(ctrl)...)
+			require.NoError(t, err)
+			lock, err := client.TryLock(context.Background(), "key1", time.Minute, time.Second)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "key1", lock.key)
+			assert.NotEmpty(t, lock.value)
+			// 有效期要扣掉时钟漂移
+			assert.True(t, lock.ValidUntil().Before(time.Now().Add(time.Minute)))
+			assert.True(t, lock.ValidUntil().After(time.Now().Add(time.Second*50)))
+		})
+	}
+}
+
+func TestNewMultiClient(t *testing.T) {
+	_, err := NewMultiClient()
+	assert.Equal(t, ErrNoClients, err)
+}
+
+func TestMultiClient_TryLockWithoutTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// timeout 是 0 的时候加锁不设置超时，回滚的时候依旧要有超时
+	expect := func(cmd *redismock.MockCmdable, script *redis.Script, res int64, wantDeadline bool) {
+		cmd.EXPECT().EvalSha(gomock.Any(), script.Hash(), []string{"key1"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+				_, ok := ctx.Deadline()
+				assert.Equal(t, wantDeadline, ok)
+				r := redis.NewCmd(context.Background())
+				r.SetVal(res)
+				return r
+			})
+	}
+	nodes := make([]redis.Cmdable, 0, 3)
+	for i := 0; i < 3; i++ {
+		cmd := redismock.NewMockCmdable(ctrl)
+		expect(cmd, luaLock, 0, false)
+		expect(cmd, luaUnlock, 0, true)
+		nodes = append(nodes, cmd)
+	}
+	client, err := NewMultiClient(nodes...)
+	require.NoError(t, err)
+	_, err = client.TryLock(context.Background(), "key1", time.Minute, 0)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+}
+
+func TestMultiClient_Lock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	n1 := mockNode(ctrl, luaLock, 2, nil, 2)
+	expectEval(n1, luaUnlock, 0, nil, 2)
+	n2 := mockNode(ctrl, luaLock, 2, nil, 2)
+	expectEval(n2, luaUnlock, 0, nil, 2)
+	n3 := mockNode(ctrl, luaLock, 1, nil, 2)
+	expectEval(n3, luaUnlock, 1, nil, 2)
+
+	client, err := NewMultiClient(n1, n2, n3)
+	require.NoError(t, err)
+	_, err = client.Lock(context.Background(), "key1", time.Minute, time.Second,
+		retry.FixedInterval(time.Millisecond, 1))
+	assert.Equal(t, fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock), err)
+}
+
+func TestMultiLock_Refresh(t *testing.T) {
+	nodeErr := errors.New("node down")
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) []redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "majority refreshed",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaRefresh, 1, nil, 1),
+					mockNode(ctrl, luaRefresh, 0, nil, 1),
+					mockNode(ctrl, luaRefresh, 1, nil, 1),
+				}
+			},
+		},
+		{
+			name: "lock lost",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaRefresh, 1, nil, 1),
+					mockNode(ctrl, luaRefresh, 0, nil, 1),
+					mockNode(ctrl, luaRefresh, 0, nil, 1),
+				}
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "nodes down",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaRefresh, 1, nil, 1),
+					mockNode(ctrl, luaRefresh, 0, nodeErr, 1),
+					mockNode(ctrl, luaRefresh, 0, nodeErr, 1),
+				}
+			},
+			wantErr: errors.Join(nodeErr, nodeErr),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := &MultiLock{
+				client:     &MultiClient{clients: tc.mock(ctrl), driftFactor: defaultDriftFactor},
+				key:        "key1",
+				value:      "value1",
+				expiration: time.Minute,
+				timeout:    time.Second,
+			}
+			err := lock.Refresh(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.True(t, lock.ValidUntil().After(time.Now().Add(time.Second*50)))
+		})
+	}
+}
+
+func TestMultiLock_Unlock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) []redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "majority released",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaUnlock, 1, nil, 1),
+					mockNode(ctrl, luaUnlock, 0, context.DeadlineExceeded, 1),
+					mockNode(ctrl, luaUnlock, 1, nil, 1),
+				}
+			},
+		},
+		{
+			name: "lock lost",
+			mock: func(ctrl *gomock.Controller) []redis.Cmdable {
+				return []redis.Cmdable{
+					mockNode(ctrl, luaUnlock, 1, nil, 1),
+					mockNode(ctrl, luaUnlock, 0, nil, 1),
+					mockNode(ctrl, luaUnlock, 0, nil, 1),
+				}
+			},
+			wantErr: ErrLockNotHeld,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := &MultiLock{
+				client:     &MultiClient{clients: tc.mock(ctrl), driftFactor: defaultDriftFactor},
+				key:        "key1",
+				value:      "value1",
+				expiration: time.Minute,
+				timeout:    time.Second,
+			}
+			assert.Equal(t, tc.wantErr, lock.Unlock(context.Background()))
+		})
+	}
+}
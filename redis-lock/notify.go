@@ -0,0 +1,100 @@
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"sync"
+)
+
+// Subscriber 能够订阅频道的客户端，*redis.Client、*redis.ClusterClient 都满足这个接口
+type Subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// unlockChannel 释放锁的时候会往这个频道发消息，要和 lua/unlock.lua、lua/force_release.lua 保持一致
+func unlockChannel(key string) string {
+	return "redis-lock:unlock:" + key
+}
+
+// publishFlag 传给释放锁的 lua 脚本，为 1 的时候脚本才会发消息
+// 订阅的人可能在别的进程里面，本进程有没有订阅说明不了什么，
+// 所以是不是 PUBLISH 由 WithPublishOnRelease 和 WithNotification 显式决定
+func (c *Client) publishFlag() int {
+	if c.publish {
+		return 1
+	}
+	return 0
+}
+
+// notifier 订阅锁释放的消息，唤醒本进程里面等待这把锁的人
+// 所有的 key 共用一个订阅连接，没有人等待的时候就关掉连接
+type notifier struct {
+	sub    Subscriber
+	lock   sync.Mutex
+	pubsub *redis.PubSub
+	// 频道 => 等待者
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+func newNotifier(sub Subscriber) *notifier {
+	return &notifier{
+		sub:     sub,
+		waiters: make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// wait 开始等待 key 被释放，返回的 channel 在收到释放消息的时候会有信号
+// 调用者不再等待的时候必须调用 cancel
+// 订阅是异步生效的，订阅生效之前的释放消息会丢失，所以调用者依旧需要兜底的重试
+func (n *notifier) wait(ctx context.Context, key string) (<-chan struct{}, func()) {
+	channel := unlockChannel(key)
+	ch := make(chan struct{}, 1)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.pubsub == nil {
+		n.pubsub = n.sub.Subscribe(ctx)
+		go n.dispatch(n.pubsub.Channel())
+	}
+	ws, ok := n.waiters[channel]
+	if !ok {
+		ws = make(map[chan struct{}]struct{})
+		n.waiters[channel] = ws
+		// 订阅失败也没关系，调用者会按照重试策略兜底
+		_ = n.pubsub.Subscribe(ctx, channel)
+	}
+	ws[ch] = struct{}{}
+
+	return ch, func() {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		ws := n.waiters[channel]
+		delete(ws, ch)
+		if len(ws) > 0 {
+			return
+		}
+		delete(n.waiters, channel)
+		if len(n.waiters) > 0 {
+			_ = n.pubsub.Unsubscribe(context.Background(), channel)
+			return
+		}
+		// 没有人在等待了，关掉订阅连接
+		_ = n.pubsub.Close()
+		n.pubsub = nil
+	}
+}
+
+// dispatch 把释放消息转发给所有等待这把锁的人，订阅连接关闭之后退出
+func (n *notifier) dispatch(msgs <-chan *redis.Message) {
+	for msg := range msgs {
+		n.lock.Lock()
+		for ch := range n.waiters[msg.Channel] {
+			select {
+			case ch <- struct{}{}:
+			default:
+				// 已经有一个信号没被处理了，不需要重复通知
+			}
+		}
+		n.lock.Unlock()
+	}
+}
@@ -0,0 +1,43 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_LockWithNotification(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithNotification(rdb))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "notify-key1"
+
+	holder, err := client.TryLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(time.Millisecond * 500)
+		assert.NoError(t, holder.Unlock(context.Background()))
+	}()
+
+	// 重试间隔很长，只有收到通知才能及时拿到锁
+	start := time.Now()
+	lock, err := client.Lock(ctx, key, time.Minute, time.Second,
+		retry.FixedInterval(time.Second*5, 1))
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) < time.Second*2)
+	require.NoError(t, lock.Unlock(ctx))
+
+	// 没有人等待之后，订阅连接会被关掉
+	client.notifier.lock.Lock()
+	assert.Nil(t, client.notifier.pubsub)
+	client.notifier.lock.Unlock()
+}
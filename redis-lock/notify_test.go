@@ -0,0 +1,134 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestRetryLock_Wake(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 第几次尝试加锁成功，0 代表一直失败
+		successAt int
+		// 唤醒的次数
+		wakeCnt  int
+		retry    retry.Factory
+		timeout  time.Duration
+		wantErr  error
+		wantCnt  int
+		maxDelay time.Duration
+	}{
+		{
+			name:      "woken up before retry interval",
+			successAt: 2,
+			wakeCnt:   1,
+			retry:     retry.FixedInterval(time.Minute, 1),
+			timeout:   time.Second * 5,
+			wantCnt:   2,
+			maxDelay:  time.Second,
+		},
+		{
+			name:      "woken up retries do not consume retry strategy",
+			successAt: 4,
+			wakeCnt:   3,
+			retry:     retry.FixedInterval(time.Minute, 1),
+			timeout:   time.Second * 5,
+			wantCnt:   4,
+			maxDelay:  time.Second,
+		},
+		{
+			name:      "fallback to retry interval",
+			successAt: 2,
+			retry:     retry.FixedInterval(time.Millisecond*100, 1),
+			timeout:   time.Second * 5,
+			wantCnt:   2,
+			maxDelay:  time.Second,
+		},
+		{
+			name:     "ctx timeout while waiting",
+			wakeCnt:  0,
+			retry:    retry.FixedInterval(time.Minute, 1),
+			timeout:  time.Millisecond * 100,
+			wantErr:  context.DeadlineExceeded,
+			wantCnt:  1,
+			maxDelay: time.Second,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			wake := make(chan struct{}, 1)
+			cnt := 0
+			start := time.Now()
+			err := retryLock(ctx, time.Second, tc.retry(), wake, func(ctx context.Context) (bool, error) {
+				cnt++
+				if cnt == tc.successAt {
+					return true, nil
+				}
+				if cnt <= tc.wakeCnt {
+					// 模拟别人释放了锁
+					go func() {
+						time.Sleep(time.Millisecond * 10)
+						wake <- struct{}{}
+					}()
+				}
+				return false, nil
+			})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+			assert.True(t, time.Since(start) < tc.maxDelay)
+		})
+	}
+}
+
+func TestClient_PublishFlag(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []ClientOption
+		wantPublish int
+	}{
+		{
+			name:        "without notification",
+			wantPublish: 0,
+		},
+		{
+			name:        "with notification",
+			opts:        []ClientOption{WithNotification(redis.NewClient(&redis.Options{}))},
+			wantPublish: 1,
+		},
+		{
+			// 等待的人在别的进程里面，本进程不订阅也要发消息
+			name:        "publish only",
+			opts:        []ClientOption{WithPublishOnRelease()},
+			wantPublish: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := redismock.NewMockCmdable(ctrl)
+			ok := redis.NewCmd(context.Background())
+			ok.SetVal(int64(1))
+			cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Return(ok)
+			cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, sha string, keys []string, args ...any) *redis.Cmd {
+					// 只有显式开启了发消息，释放锁的时候才会 PUBLISH
+					assert.Equal(t, tc.wantPublish, args[1])
+					return ok
+				})
+			client := NewClient(cmd, append(tc.opts, WithoutWatchdog())...)
+			l, err := client.TryLock(context.Background(), "key1", time.Minute)
+			require.NoError(t, err)
+			require.NoError(t, l.Unlock(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package redis_lock
+
+import (
+	"context"
+	"time"
+)
+
+// Observer 观察锁的事件，用来接入日志、监控和链路追踪
+// 方法在加锁、续约、释放的路径上同步调用，不能阻塞，也不能调用锁本身的方法。
+// 只关心一部分事件的时候可以组合 NopObserver。
+// redis-lock/observer 里面有 log/slog 以及 OpenTelemetry 风格的实现
+type Observer interface {
+	// OnAttempt 每一次访问 Redis 加锁之后调用，包括重试
+	OnAttempt(ctx context.Context, e AttemptEvent)
+	// OnAcquire Lock、TryLock、公平锁以及 Election.Campaign 返回之前调用，不管有没有拿到锁
+	OnAcquire(ctx context.Context, e AcquireEvent)
+	// OnRefresh 每一次续约之后调用，包括看门狗的续约
+	OnRefresh(ctx context.Context, e RefreshEvent)
+	// OnLost 持有期间锁丢失了，见 Lock.Lost
+	OnLost(ctx context.Context, e LostEvent)
+	// OnRelease Unlock 或者 Transfer 成功之后调用
+	OnRelease(ctx context.Context, e ReleaseEvent)
+}
+
+// AttemptEvent 一次加锁请求
+type AttemptEvent struct {
+	Key string
+	// Attempt 第几次尝试，从 1 开始
+	Attempt int
+	// Latency 这一次请求的耗时
+	Latency time.Duration
+	// Contended 锁被别人持有，Err 不为 nil 的时候一定是 false
+	Contended bool
+	Err       error
+}
+
+// AcquireEvent 一次 Lock 或者 TryLock 调用
+type AcquireEvent struct {
+	Key string
+	// Attempts 访问 Redis 的次数，开启了 WithLocalCoalescing 的时候，本地没有排到的话是 0
+	Attempts int
+	// Retries 消耗的重试次数，也就是 Attempts - 1
+	Retries int
+	// Latency 从调用到返回的耗时，包括本地排队和重试等待的时间
+	Latency time.Duration
+	// Err 为 nil 代表拿到了锁
+	Err error
+}
+
+// RefreshEvent 一次续约
+type RefreshEvent struct {
+	Key     string
+	Latency time.Duration
+	// Err 为 nil 代表续约成功
+	Err error
+}
+
+// LostEvent 锁丢失了
+type LostEvent struct {
+	Key string
+	// Held 从拿到锁到丢失的时间
+	Held time.Duration
+	// Err 丢失的原因，包装了 ErrLockLost
+	Err error
+}
+
+// ReleaseEvent 一次 Unlock
+type ReleaseEvent struct {
+	Key string
+	// Held 从拿到锁到释放的时间
+	Held time.Duration
+	// Err 为 nil 代表释放成功
+	Err error
+}
+
+// WithObserver 观察锁的事件，多次调用的时候所有的 Observer 都会收到事件
+func WithObserver(o Observer) ClientOption {
+	return func(c *Client) {
+		if c.observer == nil {
+			c.observer = o
+			return
+		}
+		if m, ok := c.observer.(multiObserver); ok {
+			c.observer = append(m, o)
+			return
+		}
+		c.observer = multiObserver{c.observer, o}
+	}
+}
+
+// NopObserver 什么都不做，可以嵌入到只关心一部分事件的 Observer 里面
+type NopObserver struct{}
+
+func (NopObserver) OnAttempt(ctx context.Context, e AttemptEvent) {}
+
+func (NopObserver) OnAcquire(ctx context.Context, e AcquireEvent) {}
+
+func (NopObserver) OnRefresh(ctx context.Context, e RefreshEvent) {}
+
+func (NopObserver) OnLost(ctx context.Context, e LostEvent) {}
+
+func (NopObserver) OnRelease(ctx context.Context, e ReleaseEvent) {}
+
+type multiObserver []Observer
+
+func (m multiObserver) OnAttempt(ctx context.Context, e AttemptEvent) {
+	for _, o := range m {
+		o.OnAttempt(ctx, e)
+	}
+}
+
+func (m multiObserver) OnAcquire(ctx context.Context, e AcquireEvent) {
+	for _, o := range m {
+		o.OnAcquire(ctx, e)
+	}
+}
+
+func (m multiObserver) OnRefresh(ctx context.Context, e RefreshEvent) {
+	for _, o := range m {
+		o.OnRefresh(ctx, e)
+	}
+}
+
+func (m multiObserver) OnLost(ctx context.Context, e LostEvent) {
+	for _, o := range m {
+		o.OnLost(ctx, e)
+	}
+}
+
+func (m multiObserver) OnRelease(ctx context.Context, e ReleaseEvent) {
+	for _, o := range m {
+		o.OnRelease(ctx, e)
+	}
+}
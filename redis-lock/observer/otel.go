@@ -0,0 +1,198 @@
+package observer
+
+import (
+	"context"
+	"errors"
+	redislock "github.com/Jared-lu/GXT/redis-lock"
+	"time"
+)
+
+// 日志字段、指标和 span 的属性名
+const (
+	AttrKey       = "lock.key"
+	AttrAttempt   = "lock.attempt"
+	AttrAttempts  = "lock.attempts"
+	AttrRetries   = "lock.retries"
+	AttrLatency   = "lock.latency"
+	AttrContended = "lock.contended"
+	AttrHeld      = "lock.held"
+	AttrResult    = "lock.result"
+	AttrError     = "error"
+)
+
+// 指标的 lock.result 属性
+const (
+	ResultAcquired  = "acquired"
+	ResultContended = "contended"
+	ResultError     = "error"
+	ResultOK        = "ok"
+)
+
+// 下面几个接口是 OpenTelemetry 对应接口的子集，用几行代码就能适配，
+// 这样 redis-lock 本身不用依赖 OpenTelemetry
+
+// Attribute 指标和 span 的属性，对应 attribute.KeyValue
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Counter 对应 metric.Int64Counter
+type Counter interface {
+	Add(ctx context.Context, incr int64, attrs ...Attribute)
+}
+
+// Histogram 对应 metric.Float64Histogram
+type Histogram interface {
+	Record(ctx context.Context, value float64, attrs ...Attribute)
+}
+
+// Tracer 对应 trace.Tracer，start 是 span 的开始时间
+type Tracer interface {
+	Start(ctx context.Context, name string, start time.Time, attrs ...Attribute) Span
+}
+
+// Span 对应 trace.Span，end 是 span 的结束时间
+type Span interface {
+	RecordError(err error)
+	End(end time.Time)
+}
+
+// Metrics 锁的指标，为 nil 的指标不会记录
+// 所有的指标都带有 lock.key 属性，key 很多的时候可以在适配的时候去掉它
+type Metrics struct {
+	// Attempts 访问 Redis 加锁的次数，lock.result 是 acquired、contended 或者 error
+	Attempts Counter
+	// AcquireLatency 每次 Lock、TryLock 的耗时，单位秒，lock.result 是 acquired 或者 error
+	AcquireLatency Histogram
+	// Retries 消耗的重试次数
+	Retries Counter
+	// Contention 加锁的时候锁被别人持有的次数
+	Contention Counter
+	// Refreshes 续约的次数，lock.result 是 ok 或者 error
+	Refreshes Counter
+	// Lost 锁丢失的次数
+	Lost Counter
+	// HoldDuration 释放或者丢失的时候锁持有的时长，单位秒
+	HoldDuration Histogram
+}
+
+// MetricsObserver 把锁的事件记录成指标
+type MetricsObserver struct {
+	m Metrics
+}
+
+var _ redislock.Observer = (*MetricsObserver)(nil)
+
+func NewMetricsObserver(m Metrics) *MetricsObserver {
+	return &MetricsObserver{m: m}
+}
+
+func (o *MetricsObserver) OnAttempt(ctx context.Context, e redislock.AttemptEvent) {
+	result := ResultAcquired
+	switch {
+	case e.Err != nil:
+		result = ResultError
+	case e.Contended:
+		result = ResultContended
+		if o.m.Contention != nil {
+			o.m.Contention.Add(ctx, 1, Attribute{Key: AttrKey, Value: e.Key})
+		}
+	}
+	if o.m.Attempts != nil {
+		o.m.Attempts.Add(ctx, 1, Attribute{Key: AttrKey, Value: e.Key}, Attribute{Key: AttrResult, Value: result})
+	}
+}
+
+func (o *MetricsObserver) OnAcquire(ctx context.Context, e redislock.AcquireEvent) {
+	if o.m.AcquireLatency != nil {
+		o.m.AcquireLatency.Record(ctx, e.Latency.Seconds(),
+			Attribute{Key: AttrKey, Value: e.Key}, Attribute{Key: AttrResult, Value: result(e.Err, ResultAcquired)})
+	}
+	if o.m.Retries != nil && e.Retries > 0 {
+		o.m.Retries.Add(ctx, int64(e.Retries), Attribute{Key: AttrKey, Value: e.Key})
+	}
+}
+
+func (o *MetricsObserver) OnRefresh(ctx context.Context, e redislock.RefreshEvent) {
+	if o.m.Refreshes != nil {
+		o.m.Refreshes.Add(ctx, 1,
+			Attribute{Key: AttrKey, Value: e.Key}, Attribute{Key: AttrResult, Value: result(e.Err, ResultOK)})
+	}
+}
+
+func (o *MetricsObserver) OnLost(ctx context.Context, e redislock.LostEvent) {
+	if o.m.Lost != nil {
+		o.m.Lost.Add(ctx, 1, Attribute{Key: AttrKey, Value: e.Key})
+	}
+	if o.m.HoldDuration != nil {
+		o.m.HoldDuration.Record(ctx, e.Held.Seconds(), Attribute{Key: AttrKey, Value: e.Key})
+	}
+}
+
+func (o *MetricsObserver) OnRelease(ctx context.Context, e redislock.ReleaseEvent) {
+	if o.m.HoldDuration != nil {
+		o.m.HoldDuration.Record(ctx, e.Held.Seconds(), Attribute{Key: AttrKey, Value: e.Key})
+	}
+}
+
+func result(err error, ok string) string {
+	if err != nil {
+		return ResultError
+	}
+	return ok
+}
+
+// TracingObserver 把锁的事件记录成 span
+// 事件发生之后才会通知，所以 span 的开始时间是按照耗时倒推出来的：
+//   - redis-lock.acquire 一次 Lock 或者 TryLock
+//   - redis-lock.refresh 一次续约
+//   - redis-lock.hold 从拿到锁到释放或者丢失
+type TracingObserver struct {
+	redislock.NopObserver
+	tracer Tracer
+}
+
+var _ redislock.Observer = (*TracingObserver)(nil)
+
+func NewTracingObserver(tracer Tracer) *TracingObserver {
+	return &TracingObserver{tracer: tracer}
+}
+
+func (o *TracingObserver) OnAcquire(ctx context.Context, e redislock.AcquireEvent) {
+	now := time.Now()
+	span := o.tracer.Start(ctx, "redis-lock.acquire", now.Add(-e.Latency),
+		Attribute{Key: AttrKey, Value: e.Key},
+		Attribute{Key: AttrAttempts, Value: e.Attempts},
+		Attribute{Key: AttrRetries, Value: e.Retries})
+	// 锁被别人持有不算错误
+	if e.Err != nil && !errors.Is(e.Err, redislock.ErrFailedToPreemptLock) {
+		span.RecordError(e.Err)
+	}
+	span.End(now)
+}
+
+func (o *TracingObserver) OnRefresh(ctx context.Context, e redislock.RefreshEvent) {
+	now := time.Now()
+	span := o.tracer.Start(ctx, "redis-lock.refresh", now.Add(-e.Latency), Attribute{Key: AttrKey, Value: e.Key})
+	if e.Err != nil {
+		span.RecordError(e.Err)
+	}
+	span.End(now)
+}
+
+func (o *TracingObserver) OnLost(ctx context.Context, e redislock.LostEvent) {
+	now := time.Now()
+	span := o.tracer.Start(ctx, "redis-lock.hold", now.Add(-e.Held), Attribute{Key: AttrKey, Value: e.Key})
+	span.RecordError(e.Err)
+	span.End(now)
+}
+
+func (o *TracingObserver) OnRelease(ctx context.Context, e redislock.ReleaseEvent) {
+	now := time.Now()
+	span := o.tracer.Start(ctx, "redis-lock.hold", now.Add(-e.Held), Attribute{Key: AttrKey, Value: e.Key})
+	if e.Err != nil {
+		span.RecordError(e.Err)
+	}
+	span.End(now)
+}
@@ -0,0 +1,125 @@
+package observer
+
+import (
+	"context"
+	"errors"
+	redislock "github.com/Jared-lu/GXT/redis-lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type record struct {
+	value float64
+	attrs []Attribute
+}
+
+type fakeCounter struct {
+	records []record
+}
+
+func (f *fakeCounter) Add(ctx context.Context, incr int64, attrs ...Attribute) {
+	f.records = append(f.records, record{value: float64(incr), attrs: attrs})
+}
+
+type fakeHistogram struct {
+	records []record
+}
+
+func (f *fakeHistogram) Record(ctx context.Context, value float64, attrs ...Attribute) {
+	f.records = append(f.records, record{value: value, attrs: attrs})
+}
+
+func TestMetricsObserver(t *testing.T) {
+	attempts, retries, contention, refreshes, lost := &fakeCounter{}, &fakeCounter{}, &fakeCounter{}, &fakeCounter{}, &fakeCounter{}
+	latency, hold := &fakeHistogram{}, &fakeHistogram{}
+	var o redislock.Observer = NewMetricsObserver(Metrics{
+		Attempts:       attempts,
+		AcquireLatency: latency,
+		Retries:        retries,
+		Contention:     contention,
+		Refreshes:      refreshes,
+		Lost:           lost,
+		HoldDuration:   hold,
+	})
+	ctx := context.Background()
+	key := Attribute{Key: AttrKey, Value: "key1"}
+	o.OnAttempt(ctx, redislock.AttemptEvent{Key: "key1", Attempt: 1, Contended: true})
+	o.OnAttempt(ctx, redislock.AttemptEvent{Key: "key1", Attempt: 2, Err: errors.New("network error")})
+	o.OnAttempt(ctx, redislock.AttemptEvent{Key: "key1", Attempt: 3})
+	o.OnAcquire(ctx, redislock.AcquireEvent{Key: "key1", Attempts: 3, Retries: 2, Latency: time.Millisecond * 1500})
+	o.OnRefresh(ctx, redislock.RefreshEvent{Key: "key1"})
+	o.OnRefresh(ctx, redislock.RefreshEvent{Key: "key1", Err: redislock.ErrLockNotHeld})
+	o.OnLost(ctx, redislock.LostEvent{Key: "key1", Held: time.Second * 2, Err: redislock.ErrLockLost})
+	o.OnRelease(ctx, redislock.ReleaseEvent{Key: "key1", Held: time.Second * 3})
+
+	assert.Equal(t, []record{
+		{value: 1, attrs: []Attribute{key, {Key: AttrResult, Value: ResultContended}}},
+		{value: 1, attrs: []Attribute{key, {Key: AttrResult, Value: ResultError}}},
+		{value: 1, attrs: []Attribute{key, {Key: AttrResult, Value: ResultAcquired}}},
+	}, attempts.records)
+	assert.Equal(t, []record{{value: 1, attrs: []Attribute{key}}}, contention.records)
+	assert.Equal(t, []record{{value: 2, attrs: []Attribute{key}}}, retries.records)
+	assert.Equal(t, []record{
+		{value: 1.5, attrs: []Attribute{key, {Key: AttrResult, Value: ResultAcquired}}},
+	}, latency.records)
+	assert.Equal(t, []record{
+		{value: 1, attrs: []Attribute{key, {Key: AttrResult, Value: ResultOK}}},
+		{value: 1, attrs: []Attribute{key, {Key: AttrResult, Value: ResultError}}},
+	}, refreshes.records)
+	assert.Equal(t, []record{{value: 1, attrs: []Attribute{key}}}, lost.records)
+	assert.Equal(t, []record{{value: 2, attrs: []Attribute{key}}, {value: 3, attrs: []Attribute{key}}}, hold.records)
+
+	// 没有配置的指标不记录
+	o = NewMetricsObserver(Metrics{})
+	o.OnAttempt(ctx, redislock.AttemptEvent{Key: "key1", Contended: true})
+	o.OnRelease(ctx, redislock.ReleaseEvent{Key: "key1"})
+}
+
+type fakeSpan struct {
+	name  string
+	start time.Time
+	end   time.Time
+	err   error
+}
+
+func (f *fakeSpan) RecordError(err error) {
+	f.err = err
+}
+
+func (f *fakeSpan) End(end time.Time) {
+	f.end = end
+}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (f *fakeTracer) Start(ctx context.Context, name string, start time.Time, attrs ...Attribute) Span {
+	span := &fakeSpan{name: name, start: start}
+	f.spans = append(f.spans, span)
+	return span
+}
+
+func TestTracingObserver(t *testing.T) {
+	tracer := &fakeTracer{}
+	o := NewTracingObserver(tracer)
+	ctx := context.Background()
+	o.OnAttempt(ctx, redislock.AttemptEvent{Key: "key1"})
+	o.OnAcquire(ctx, redislock.AcquireEvent{Key: "key1", Latency: time.Second, Err: redislock.ErrFailedToPreemptLock})
+	o.OnRefresh(ctx, redislock.RefreshEvent{Key: "key1", Latency: time.Millisecond, Err: redislock.ErrLockNotHeld})
+	o.OnRelease(ctx, redislock.ReleaseEvent{Key: "key1", Held: time.Minute})
+
+	require.Len(t, tracer.spans, 3)
+	acquire, refresh, hold := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	assert.Equal(t, "redis-lock.acquire", acquire.name)
+	assert.Equal(t, time.Second, acquire.end.Sub(acquire.start))
+	// 锁被别人持有不算错误
+	assert.NoError(t, acquire.err)
+	assert.Equal(t, "redis-lock.refresh", refresh.name)
+	assert.Equal(t, redislock.ErrLockNotHeld, refresh.err)
+	assert.Equal(t, "redis-lock.hold", hold.name)
+	assert.Equal(t, time.Minute, hold.end.Sub(hold.start))
+	assert.NoError(t, hold.err)
+}
@@ -0,0 +1,88 @@
+package observer
+
+import (
+	"context"
+	"errors"
+	redislock "github.com/Jared-lu/GXT/redis-lock"
+	"log/slog"
+)
+
+// SlogObserver 用 log/slog 输出锁的事件
+// 正常的事件是 Debug，加锁失败、续约失败、释放失败是 Warn，锁丢失是 Error。
+// 锁被别人持有导致的加锁失败是正常的竞争，用 Info
+type SlogObserver struct {
+	logger *slog.Logger
+}
+
+var _ redislock.Observer = (*SlogObserver)(nil)
+
+// NewSlogObserver logger 为 nil 的时候使用 slog.Default()
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogObserver{logger: logger}
+}
+
+func (s *SlogObserver) OnAttempt(ctx context.Context, e redislock.AttemptEvent) {
+	attrs := []slog.Attr{
+		slog.String(AttrKey, e.Key),
+		slog.Int(AttrAttempt, e.Attempt),
+		slog.Duration(AttrLatency, e.Latency),
+		slog.Bool(AttrContended, e.Contended),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any(AttrError, e.Err))
+	}
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "redis-lock: 尝试加锁", attrs...)
+}
+
+func (s *SlogObserver) OnAcquire(ctx context.Context, e redislock.AcquireEvent) {
+	attrs := []slog.Attr{
+		slog.String(AttrKey, e.Key),
+		slog.Int(AttrAttempts, e.Attempts),
+		slog.Int(AttrRetries, e.Retries),
+		slog.Duration(AttrLatency, e.Latency),
+	}
+	switch {
+	case e.Err == nil:
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "redis-lock: 加锁成功", attrs...)
+	case errors.Is(e.Err, redislock.ErrFailedToPreemptLock):
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "redis-lock: 锁被别人持有",
+			append(attrs, slog.Any(AttrError, e.Err))...)
+	default:
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "redis-lock: 加锁失败",
+			append(attrs, slog.Any(AttrError, e.Err))...)
+	}
+}
+
+func (s *SlogObserver) OnRefresh(ctx context.Context, e redislock.RefreshEvent) {
+	attrs := []slog.Attr{
+		slog.String(AttrKey, e.Key),
+		slog.Duration(AttrLatency, e.Latency),
+	}
+	if e.Err == nil {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "redis-lock: 续约成功", attrs...)
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "redis-lock: 续约失败", append(attrs, slog.Any(AttrError, e.Err))...)
+}
+
+func (s *SlogObserver) OnLost(ctx context.Context, e redislock.LostEvent) {
+	s.logger.LogAttrs(ctx, slog.LevelError, "redis-lock: 锁丢失",
+		slog.String(AttrKey, e.Key),
+		slog.Duration(AttrHeld, e.Held),
+		slog.Any(AttrError, e.Err))
+}
+
+func (s *SlogObserver) OnRelease(ctx context.Context, e redislock.ReleaseEvent) {
+	attrs := []slog.Attr{
+		slog.String(AttrKey, e.Key),
+		slog.Duration(AttrHeld, e.Held),
+	}
+	if e.Err == nil {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "redis-lock: 释放锁", attrs...)
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelWarn, "redis-lock: 释放锁失败", append(attrs, slog.Any(AttrError, e.Err))...)
+}
@@ -0,0 +1,101 @@
+package observer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	redislock "github.com/Jared-lu/GXT/redis-lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSlogObserver(t *testing.T) {
+	errNetwork := errors.New("network error")
+	testCases := []struct {
+		name    string
+		observe func(o *SlogObserver)
+
+		wantLevel string
+		wantMsg   string
+		wantAttrs map[string]any
+	}{
+		{
+			name: "acquired",
+			observe: func(o *SlogObserver) {
+				o.OnAcquire(context.Background(), redislock.AcquireEvent{
+					Key: "key1", Attempts: 3, Retries: 2, Latency: time.Millisecond,
+				})
+			},
+			wantLevel: "DEBUG",
+			wantMsg:   "redis-lock: 加锁成功",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrAttempts: float64(3), AttrRetries: float64(2)},
+		},
+		{
+			name: "contended",
+			observe: func(o *SlogObserver) {
+				o.OnAcquire(context.Background(), redislock.AcquireEvent{
+					Key: "key1", Attempts: 1, Err: redislock.ErrFailedToPreemptLock,
+				})
+			},
+			wantLevel: "INFO",
+			wantMsg:   "redis-lock: 锁被别人持有",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrError: "failed to preempt lock"},
+		},
+		{
+			name: "acquire failed",
+			observe: func(o *SlogObserver) {
+				o.OnAcquire(context.Background(), redislock.AcquireEvent{Key: "key1", Attempts: 1, Err: errNetwork})
+			},
+			wantLevel: "WARN",
+			wantMsg:   "redis-lock: 加锁失败",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrError: "network error"},
+		},
+		{
+			name: "refresh failed",
+			observe: func(o *SlogObserver) {
+				o.OnRefresh(context.Background(), redislock.RefreshEvent{Key: "key1", Err: errNetwork})
+			},
+			wantLevel: "WARN",
+			wantMsg:   "redis-lock: 续约失败",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrError: "network error"},
+		},
+		{
+			name: "lost",
+			observe: func(o *SlogObserver) {
+				o.OnLost(context.Background(), redislock.LostEvent{
+					Key: "key1", Held: time.Second, Err: redislock.ErrLockLost,
+				})
+			},
+			wantLevel: "ERROR",
+			wantMsg:   "redis-lock: 锁丢失",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrHeld: float64(time.Second), AttrError: "lock lost"},
+		},
+		{
+			name: "released",
+			observe: func(o *SlogObserver) {
+				o.OnRelease(context.Background(), redislock.ReleaseEvent{Key: "key1", Held: time.Second})
+			},
+			wantLevel: "DEBUG",
+			wantMsg:   "redis-lock: 释放锁",
+			wantAttrs: map[string]any{AttrKey: "key1", AttrHeld: float64(time.Second)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			tc.observe(NewSlogObserver(logger))
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, tc.wantLevel, record["level"])
+			assert.Equal(t, tc.wantMsg, record["msg"])
+			for k, v := range tc.wantAttrs {
+				assert.Equal(t, v, record[k], k)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordObserver 记录收到的事件
+type recordObserver struct {
+	mu       sync.Mutex
+	attempts []AttemptEvent
+	acquires []AcquireEvent
+	refreshs []RefreshEvent
+	losts    []LostEvent
+	releases []ReleaseEvent
+}
+
+func (r *recordObserver) OnAttempt(ctx context.Context, e AttemptEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, e)
+}
+
+func (r *recordObserver) OnAcquire(ctx context.Context, e AcquireEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acquires = append(r.acquires, e)
+}
+
+func (r *recordObserver) OnRefresh(ctx context.Context, e RefreshEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshs = append(r.refreshs, e)
+}
+
+func (r *recordObserver) OnLost(ctx context.Context, e LostEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.losts = append(r.losts, e)
+}
+
+func (r *recordObserver) OnRelease(ctx context.Context, e ReleaseEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releases = append(r.releases, e)
+}
+
+func TestClient_Observer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	evalRes := func(val int64) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal(val)
+		return res
+	}
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Times(2).Return(evalRes(0)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(3)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(1)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(1)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(0)),
+	)
+	obs1, obs2 := &recordObserver{}, &recordObserver{}
+	client := NewClient(cmd, WithObserver(obs1), WithObserver(obs2), WithoutWatchdog())
+	ctx := context.Background()
+
+	l, err := client.Lock(ctx, "key1", time.Minute, time.Second, retry.FixedInterval(time.Millisecond, 3))
+	require.NoError(t, err)
+	require.NoError(t, l.Refresh(ctx))
+	require.NoError(t, l.Unlock(ctx))
+	_, err = client.TryLock(ctx, "key1", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	for _, obs := range []*recordObserver{obs1, obs2} {
+		require.Len(t, obs.attempts, 4)
+		assert.True(t, obs.attempts[0].Contended)
+		assert.True(t, obs.attempts[1].Contended)
+		assert.False(t, obs.attempts[2].Contended)
+		assert.Equal(t, 3, obs.attempts[2].Attempt)
+		assert.True(t, obs.attempts[3].Contended)
+
+		require.Len(t, obs.acquires, 2)
+		assert.Equal(t, "key1", obs.acquires[0].Key)
+		assert.Equal(t, 3, obs.acquires[0].Attempts)
+		assert.Equal(t, 2, obs.acquires[0].Retries)
+		assert.NoError(t, obs.acquires[0].Err)
+		assert.Equal(t, 0, obs.acquires[1].Retries)
+		assert.Equal(t, ErrFailedToPreemptLock, obs.acquires[1].Err)
+
+		require.Len(t, obs.refreshs, 1)
+		assert.NoError(t, obs.refreshs[0].Err)
+		require.Len(t, obs.releases, 1)
+		assert.NoError(t, obs.releases[0].Err)
+		assert.Empty(t, obs.losts)
+	}
+}
+
+func TestClient_Observer_Fair(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	evalRes := func(val int64) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal(val)
+		return res
+	}
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(0)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(1)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaFairLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(0)),
+	)
+	obs := &recordObserver{}
+	client := NewClient(cmd, WithObserver(obs), WithoutWatchdog())
+	ctx := context.Background()
+
+	_, err := client.LockFair(ctx, "key1", time.Minute, time.Second, retry.FixedInterval(time.Millisecond, 3))
+	require.NoError(t, err)
+	_, err = client.TryLockFair(ctx, "key1", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	require.Len(t, obs.attempts, 3)
+	assert.True(t, obs.attempts[0].Contended)
+	assert.False(t, obs.attempts[1].Contended)
+	assert.Equal(t, 2, obs.attempts[1].Attempt)
+	assert.True(t, obs.attempts[2].Contended)
+	require.Len(t, obs.acquires, 2)
+	assert.Equal(t, 2, obs.acquires[0].Attempts)
+	assert.NoError(t, obs.acquires[0].Err)
+	assert.Equal(t, ErrFailedToPreemptLock, obs.acquires[1].Err)
+}
+
+func TestClient_Observer_Election(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	evalRes := func(val int64) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal(val)
+		return res
+	}
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(0)),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(1)),
+	)
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), gomock.Any(), gomock.Any()).AnyTimes().Return(evalRes(1))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), gomock.Any(), gomock.Any()).Return(evalRes(1))
+	obs := &recordObserver{}
+	client := NewClient(cmd, WithObserver(obs))
+	e := client.NewElection("leader", "node1", time.Minute, LeaderCallbacks{},
+		WithCampaignInterval(time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	require.NoError(t, e.Campaign(ctx))
+	require.NoError(t, e.Resign(ctx))
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	require.Len(t, obs.attempts, 2)
+	assert.True(t, obs.attempts[0].Contended)
+	assert.False(t, obs.attempts[1].Contended)
+	require.Len(t, obs.acquires, 1)
+	assert.Equal(t, "leader", obs.acquires[0].Key)
+	assert.Equal(t, 2, obs.acquires[0].Attempts)
+	assert.NoError(t, obs.acquires[0].Err)
+}
+
+func TestClient_Observer_Lost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(0))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), gomock.Any(), gomock.Any()).Return(res)
+	obs := &recordObserver{}
+	client := NewClient(cmd, WithObserver(obs), WithWatchdog(time.Millisecond*20, time.Second))
+	l := client.newLock("key1", "value1", time.Minute, 1, time.Now(), nil)
+	<-l.Context().Done()
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	require.Len(t, obs.refreshs, 1)
+	assert.ErrorIs(t, obs.refreshs[0].Err, ErrLockNotHeld)
+	require.Len(t, obs.losts, 1)
+	assert.Equal(t, "key1", obs.losts[0].Key)
+	assert.ErrorIs(t, obs.losts[0].Err, ErrLockLost)
+	assert.Greater(t, obs.losts[0].Held, time.Duration(0))
+}
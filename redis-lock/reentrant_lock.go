@@ -0,0 +1,114 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed lua/reentrant_lock.lua
+var luaReentrantLockSrc string
+var luaReentrantLock = redis.NewScript(luaReentrantLockSrc)
+
+//go:embed lua/reentrant_unlock.lua
+var luaReentrantUnlockSrc string
+var luaReentrantUnlock = redis.NewScript(luaReentrantUnlockSrc)
+
+//go:embed lua/reentrant_refresh.lua
+var luaReentrantRefreshSrc string
+var luaReentrantRefresh = redis.NewScript(luaReentrantRefreshSrc)
+
+// TryLockReentrant 尝试加可重入锁
+// owner 是持有者的标识，同一个 owner 可以对同一个 key 重复加锁，
+// 每一次加锁都要对应一次 Unlock，全部 Unlock 之后才会真正释放锁
+func (c *Client) TryLockReentrant(ctx context.Context, key string,
+	owner string, expiration time.Duration) (*ReentrantLock, error) {
+	ok, err := c.tryLockReentrant(ctx, key, owner, expiration)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 别人抢到了锁
+		return nil, ErrFailedToPreemptLock
+	}
+	return newReentrantLock(c.client, key, owner, expiration), nil
+}
+
+// LockReentrant 加可重入锁，锁被别人持有时按照 factory 创建的重试策略重试
+// timeout 是每一次加锁的超时时间
+func (c *Client) LockReentrant(ctx context.Context, key string, owner string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*ReentrantLock, error) {
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		return c.tryLockReentrant(ctx, key, owner, expiration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newReentrantLock(c.client, key, owner, expiration), nil
+}
+
+func (c *Client) tryLockReentrant(ctx context.Context, key string,
+	owner string, expiration time.Duration) (bool, error) {
+	res, err := luaReentrantLock.Run(ctx, c.client, []string{key}, owner, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	// 返回的是加锁之后的重入次数
+	return res > 0, nil
+}
+
+// ReentrantLock 可重入锁的一次持有
+// 同一个 owner 每加一次锁就会得到一个 ReentrantLock，各自 Unlock 一次
+type ReentrantLock struct {
+	client redis.Cmdable
+	// key + owner 才是锁的唯一标识
+	key        string
+	owner      string
+	expiration time.Duration
+	// 这一次持有是否已经释放，防止同一次持有被 Unlock 多次
+	released atomic.Bool
+}
+
+func newReentrantLock(client redis.Cmdable, key string,
+	owner string, expiration time.Duration) *ReentrantLock {
+	return &ReentrantLock{
+		client:     client,
+		key:        key,
+		owner:      owner,
+		expiration: expiration,
+	}
+}
+
+// Refresh 续约，会延长整个锁的过期时间，而不仅仅是这一次持有
+func (l *ReentrantLock) Refresh(ctx context.Context) error {
+	res, err := luaReentrantRefresh.Run(ctx, l.client, []string{l.key}, l.owner, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		// 不是自己的锁
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock 释放这一次持有，重入次数减为 0 的时候才会真正删除锁
+func (l *ReentrantLock) Unlock(ctx context.Context) error {
+	if !l.released.CompareAndSwap(false, true) {
+		return ErrLockNotHeld
+	}
+	res, err := luaReentrantUnlock.Run(ctx, l.client, []string{l.key}, l.owner).Int64()
+	if err != nil {
+		// 不确定有没有释放成功，允许调用方再试一次
+		l.released.Store(false)
+		return err
+	}
+	if res < 0 {
+		// 不是自己的锁
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_ReentrantLock(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "reentrant-key1"
+
+	l1, err := client.TryLockReentrant(ctx, key, "owner1", time.Minute)
+	require.NoError(t, err)
+	// 同一个 owner 可以重入
+	l2, err := client.TryLockReentrant(ctx, key, "owner1", time.Minute)
+	require.NoError(t, err)
+	cnt, err := rdb.HGet(ctx, key, "count").Int()
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+
+	// 别人加不了锁
+	_, err = client.TryLockReentrant(ctx, key, "owner2", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	// 别人也续约不了
+	other := newReentrantLock(rdb, key, "owner2", time.Minute)
+	assert.Equal(t, ErrLockNotHeld, other.Refresh(ctx))
+	assert.Equal(t, ErrLockNotHeld, other.Unlock(ctx))
+
+	require.NoError(t, l2.Refresh(ctx))
+	ttl, err := rdb.PTTL(ctx, key).Result()
+	require.NoError(t, err)
+	assert.True(t, ttl > time.Second*50)
+
+	// 释放一次之后锁还在
+	require.NoError(t, l2.Unlock(ctx))
+	assert.Equal(t, ErrLockNotHeld, l2.Unlock(ctx))
+	exists, err := rdb.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), exists)
+
+	// 全部释放之后锁才被删除
+	require.NoError(t, l1.Unlock(ctx))
+	exists, err = rdb.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+
+	l3, err := client.TryLockReentrant(ctx, key, "owner2", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, l3.Unlock(ctx))
+}
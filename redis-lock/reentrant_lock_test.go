@@ -0,0 +1,223 @@
+package redis_lock
+
+import (
+	"context"
+	"fmt"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_TryLockReentrant(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "held by others",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "reentered",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(2))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			lock, err := client.TryLockReentrant(context.Background(), "key1", "owner1", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "key1", lock.key)
+			assert.Equal(t, "owner1", lock.owner)
+			assert.Equal(t, time.Minute, lock.expiration)
+		})
+	}
+}
+
+func TestClient_LockReentrant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	held := redis.NewCmd(context.Background())
+	held.SetVal(int64(0))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key1"}, gomock.Any()).
+		Times(2).Return(held)
+	ok := redis.NewCmd(context.Background())
+	ok.SetVal(int64(1))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key1"}, gomock.Any()).Return(ok)
+
+	client := NewClient(cmd)
+	lock, err := client.LockReentrant(context.Background(), "key1", "owner1", time.Minute, time.Second,
+		retry.FixedInterval(time.Millisecond, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, "owner1", lock.owner)
+
+	cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantLock.Hash(), []string{"key2"}, gomock.Any()).
+		Times(2).Return(held)
+	_, err = client.LockReentrant(context.Background(), "key2", "owner1", time.Minute, time.Second,
+		retry.FixedInterval(time.Millisecond, 1))
+	assert.Equal(t, fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock), err)
+}
+
+func TestReentrantLock_Unlock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+		// 第二次 Unlock 的结果
+		wantAgainErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantUnlock.Hash(), []string{"key1"},
+					[]any{"owner1"}).Times(2).Return(res)
+				return cmd
+			},
+			wantErr:      context.DeadlineExceeded,
+			wantAgainErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(-1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantUnlock.Hash(), []string{"key1"},
+					[]any{"owner1"}).Return(res)
+				return cmd
+			},
+			wantErr:      ErrLockNotHeld,
+			wantAgainErr: ErrLockNotHeld,
+		},
+		{
+			name: "still held by owner",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantUnlock.Hash(), []string{"key1"},
+					[]any{"owner1"}).Return(res)
+				return cmd
+			},
+			wantAgainErr: ErrLockNotHeld,
+		},
+		{
+			name: "released",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantUnlock.Hash(), []string{"key1"},
+					[]any{"owner1"}).Return(res)
+				return cmd
+			},
+			wantAgainErr: ErrLockNotHeld,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := newReentrantLock(tc.mock(ctrl), "key1", "owner1", time.Minute)
+			err := lock.Unlock(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			// 同一次持有只能释放一次
+			err = lock.Unlock(context.Background())
+			assert.Equal(t, tc.wantAgainErr, err)
+		})
+	}
+}
+
+func TestReentrantLock_Refresh(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantRefresh.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantRefresh.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "refresh success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaReentrantRefresh.Hash(), []string{"key1"},
+					[]any{"owner1", int64(60000)}).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := newReentrantLock(tc.mock(ctrl), "key1", "owner1", time.Minute)
+			err := lock.Refresh(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -1,15 +1,26 @@
 package redis_lock
 
-import "time"
+import (
+	"github.com/Jared-lu/GXT/retry"
+	"time"
+)
 
-type RetryStrategy interface {
-	// Next
-	// 第一个返回值：重试的间隔
-	// 第二个返回值：要不要继续重试
-	Next() (time.Duration, bool)
+// RetryStrategy 加锁失败之后的重试策略，就是 retry.Strategy
+// 策略是有状态的，所以加锁的方法接收的是 retry.Factory，每次加锁都会创建一个新的策略
+type RetryStrategy = retry.Strategy
+
+// newRetryStrategy 为这一次加锁创建重试策略，factory 为 nil 的时候返回 nil，也就是只尝试一次
+func newRetryStrategy(factory retry.Factory) RetryStrategy {
+	if factory == nil {
+		return nil
+	}
+	return factory()
 }
 
 // FixedIntervalRetryStrategy 固定间隔重试
+// 重试次数用完之后不会重置，不能在多次加锁之间共享。
+//
+// Deprecated: 加锁的方法只接收 retry.Factory，使用 retry.FixedInterval
 type FixedIntervalRetryStrategy struct {
 	Interval time.Duration
 	MaxCnt   int
@@ -0,0 +1,151 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed lua/rwlock_rlock.lua
+var luaRWLockRLockSrc string
+var luaRWLockRLock = redis.NewScript(luaRWLockRLockSrc)
+
+//go:embed lua/rwlock_wlock.lua
+var luaRWLockWLockSrc string
+var luaRWLockWLock = redis.NewScript(luaRWLockWLockSrc)
+
+//go:embed lua/rwlock_refresh.lua
+var luaRWLockRefreshSrc string
+var luaRWLockRefresh = redis.NewScript(luaRWLockRefreshSrc)
+
+//go:embed lua/rwlock_unlock.lua
+var luaRWLockUnlockSrc string
+var luaRWLockUnlock = redis.NewScript(luaRWLockUnlockSrc)
+
+// TryRLock 尝试加读锁
+// 没有写者持有锁、也没有写者在等待的时候才能加锁成功，多个读者可以同时持有读锁
+func (c *Client) TryRLock(ctx context.Context, key string, expiration time.Duration) (*RWLock, error) {
+	id := uuid.New().String()
+	ok, err := c.tryRLock(ctx, key, id, expiration)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFailedToPreemptLock
+	}
+	return newRWLock(c.client, key, "r:"+id, expiration), nil
+}
+
+// RLock 加读锁，加锁失败时按照 factory 创建的重试策略重试
+// timeout 是每一次加锁的超时时间
+func (c *Client) RLock(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*RWLock, error) {
+	id := uuid.New().String()
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		return c.tryRLock(ctx, key, id, expiration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newRWLock(c.client, key, "r:"+id, expiration), nil
+}
+
+// TryWLock 尝试加写锁，只有没有任何读者和写者的时候才能加锁成功
+func (c *Client) TryWLock(ctx context.Context, key string, expiration time.Duration) (*RWLock, error) {
+	id := uuid.New().String()
+	ok, err := c.tryWLock(ctx, key, id, expiration, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFailedToPreemptLock
+	}
+	return newRWLock(c.client, key, "w:"+id, expiration), nil
+}
+
+// WLock 加写锁，加锁失败时按照 factory 创建的重试策略重试
+// 等待期间会登记为等待的写者，新来的读者都加不了锁，
+// 这样现有的读者释放之后写者一定能拿到锁，不会被源源不断的读者饿死
+// timeout 是每一次加锁的超时时间
+func (c *Client) WLock(ctx context.Context, key string,
+	expiration time.Duration, timeout time.Duration, factory retry.Factory) (*RWLock, error) {
+	id := uuid.New().String()
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		return c.tryWLock(ctx, key, id, expiration, true)
+	})
+	if err != nil {
+		// 放弃等待了，要把等待的登记删掉，不然读者要等到登记过期才能加锁
+		ctx2, cancel := cleanupContext(timeout)
+		_ = luaRWLockUnlock.Run(ctx2, c.client, []string{key}, "p:"+id).Err()
+		cancel()
+		return nil, err
+	}
+	return newRWLock(c.client, key, "w:"+id, expiration), nil
+}
+
+func (c *Client) tryRLock(ctx context.Context, key string,
+	id string, expiration time.Duration) (bool, error) {
+	res, err := luaRWLockRLock.Run(ctx, c.client, []string{key}, id, expiration.Milliseconds()).Int64()
+	return res == 1, err
+}
+
+func (c *Client) tryWLock(ctx context.Context, key string,
+	id string, expiration time.Duration, wait bool) (bool, error) {
+	waitFlag := "0"
+	if wait {
+		waitFlag = "1"
+	}
+	res, err := luaRWLockWLock.Run(ctx, c.client, []string{key}, id, expiration.Milliseconds(), waitFlag).Int64()
+	return res == 1, err
+}
+
+// RWLock 读写锁的一个持有者，可能是读者也可能是写者
+// 每个持有者都有自己的过期时间，需要各自续约
+type RWLock struct {
+	client redis.Cmdable
+	key    string
+	// 持有者在 hash 中的 field，读者是 r:<id>，写者是 w:<id>
+	field      string
+	expiration time.Duration
+}
+
+func newRWLock(client redis.Cmdable, key string, field string, expiration time.Duration) *RWLock {
+	return &RWLock{
+		client:     client,
+		key:        key,
+		field:      field,
+		expiration: expiration,
+	}
+}
+
+// IsWriter 是否是写锁
+func (l *RWLock) IsWriter() bool {
+	return l.field[0] == 'w'
+}
+
+// Refresh 续约，只会延长自己的过期时间
+func (l *RWLock) Refresh(ctx context.Context) error {
+	res, err := luaRWLockRefresh.Run(ctx, l.client, []string{l.key}, l.field, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		// 已经过期了，或者已经释放了
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *RWLock) Unlock(ctx context.Context) error {
+	res, err := luaRWLockUnlock.Run(ctx, l.client, []string{l.key}, l.field).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"fmt"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_RWLock(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "rwlock-key1"
+
+	// 多个读者可以同时持有读锁
+	r1, err := client.TryRLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	r2, err := client.TryRLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, r1.IsWriter())
+
+	// 有读者的时候加不了写锁
+	_, err = client.TryWLock(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	// TryWLock 不会登记为等待的写者，读者还能继续加锁
+	r3, err := client.TryRLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+
+	// 写者等待期间，新的读者加不了锁
+	wCh := make(chan *RWLock, 1)
+	go func() {
+		w, err := client.WLock(ctx, key, time.Minute, time.Second,
+			retry.FixedInterval(time.Millisecond*50, 100))
+		assert.NoError(t, err)
+		wCh <- w
+	}()
+	time.Sleep(time.Millisecond * 200)
+	_, err = client.TryRLock(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+
+	// 续约只影响自己
+	require.NoError(t, r1.Refresh(ctx))
+	require.NoError(t, r1.Unlock(ctx))
+	assert.Equal(t, ErrLockNotHeld, r1.Refresh(ctx))
+	assert.Equal(t, ErrLockNotHeld, r1.Unlock(ctx))
+	require.NoError(t, r2.Unlock(ctx))
+	require.NoError(t, r3.Unlock(ctx))
+
+	// 读者都释放之后写者拿到锁
+	var w *RWLock
+	select {
+	case w = <-wCh:
+	case <-ctx.Done():
+		t.Fatal("写者没有拿到锁")
+	}
+	assert.True(t, w.IsWriter())
+	_, err = client.TryRLock(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	_, err = client.TryWLock(ctx, key, time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	require.NoError(t, w.Refresh(ctx))
+	require.NoError(t, w.Unlock(ctx))
+
+	exists, err := rdb.Exists(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+}
+
+func Test_e2e_RWLock_GiveUpWaiting(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	key := "rwlock-key2"
+
+	r, err := client.TryRLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	_, err = client.WLock(ctx, key, time.Minute, time.Second,
+		retry.FixedInterval(time.Millisecond*10, 2))
+	assert.Equal(t, fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock), err)
+
+	// 写者放弃之后，读者又可以加锁了
+	r2, err := client.TryRLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, r.Unlock(ctx))
+	require.NoError(t, r2.Unlock(ctx))
+}
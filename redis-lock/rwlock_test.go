@@ -0,0 +1,274 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_TryRLock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRLock.Hash(), []string{"key1"}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "writer holds or waits",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRLock.Hash(), []string{"key1"}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRLock.Hash(), []string{"key1"}, gomock.Any()).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			lock, err := client.TryRLock(context.Background(), "key1", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.False(t, lock.IsWriter())
+			assert.Equal(t, "key1", lock.key)
+		})
+	}
+}
+
+func TestClient_TryWLock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockWLock.Hash(), []string{"key1"}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "held by others",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				// TryWLock 不会登记为等待的写者
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockWLock.Hash(), []string{"key1"},
+					gomock.Any(), int64(60000), "0").Return(res)
+				return cmd
+			},
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockWLock.Hash(), []string{"key1"}, gomock.Any()).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			lock, err := client.TryWLock(context.Background(), "key1", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.True(t, lock.IsWriter())
+		})
+	}
+}
+
+func TestClient_WLock(t *testing.T) {
+	// timeout <= 0 代表每一次加锁不设置超时，清理的时候依旧要能访问 Redis
+	for _, timeout := range []time.Duration{time.Second, 0} {
+		t.Run(timeout.String(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := redismock.NewMockCmdable(ctrl)
+			held := redis.NewCmd(context.Background())
+			held.SetVal(int64(0))
+			cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockWLock.Hash(), []string{"key1"},
+				gomock.Any(), int64(60000), "1").Times(2).Return(held)
+			// 放弃等待的时候要删掉等待的登记
+			cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockUnlock.Hash(), []string{"key1"}, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+					assert.NoError(t, ctx.Err())
+					assert.Equal(t, byte('p'), args[0].(string)[0])
+					res := redis.NewCmd(context.Background())
+					res.SetVal(int64(1))
+					return res
+				})
+
+			client := NewClient(cmd)
+			_, err := client.WLock(context.Background(), "key1", time.Minute, timeout,
+				retry.FixedInterval(time.Millisecond, 1))
+			assert.ErrorIs(t, err, ErrFailedToPreemptLock)
+		})
+	}
+}
+
+func TestClient_RLock_SharedRetryFactory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	held := redis.NewCmd(context.Background())
+	held.SetVal(int64(0))
+	// 每次调用都会创建新的重试策略，所以两次调用都会重试一次
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRLock.Hash(), []string{"key1"}, gomock.Any()).
+		Times(4).Return(held)
+
+	client := NewClient(cmd)
+	factory := retry.FixedInterval(time.Millisecond, 1)
+	for i := 0; i < 2; i++ {
+		_, err := client.RLock(context.Background(), "key1", time.Minute, time.Second, factory)
+		assert.ErrorIs(t, err, ErrFailedToPreemptLock)
+	}
+}
+
+func TestRWLock_Refresh(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRefresh.Hash(), []string{"key1"},
+					[]any{"r:id1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRefresh.Hash(), []string{"key1"},
+					[]any{"r:id1", int64(60000)}).Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockRefresh.Hash(), []string{"key1"},
+					[]any{"r:id1", int64(60000)}).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := newRWLock(tc.mock(ctrl), "key1", "r:id1", time.Minute)
+			assert.Equal(t, tc.wantErr, lock.Refresh(context.Background()))
+		})
+	}
+}
+
+func TestRWLock_Unlock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockUnlock.Hash(), []string{"key1"},
+					[]any{"w:id1"}).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockUnlock.Hash(), []string{"key1"},
+					[]any{"w:id1"}).Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRWLockUnlock.Hash(), []string{"key1"},
+					[]any{"w:id1"}).Return(res)
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lock := newRWLock(tc.mock(ctrl), "key1", "w:id1", time.Minute)
+			assert.Equal(t, tc.wantErr, lock.Unlock(context.Background()))
+		})
+	}
+}
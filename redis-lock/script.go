@@ -0,0 +1,42 @@
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// scripts 所有的 lua 脚本，LoadScripts 会把它们全部加载到 Redis 上
+// 所有的脚本都通过 redis.Script.Run 执行，优先使用 EVALSHA，
+// 服务器上没有脚本的时候（返回 NOSCRIPT）自动退化为 EVAL，EVAL 之后脚本就被缓存了
+func scripts() []*redis.Script {
+	return []*redis.Script{
+		luaLock, luaRefresh, luaUnlock, luaTTL, luaInspect, luaForceRelease, luaTransfer,
+		luaFairLock, luaFairLeave,
+		luaLockMulti, luaRefreshMulti, luaUnlockMulti,
+		luaReentrantLock, luaReentrantRefresh, luaReentrantUnlock,
+		luaRWLockRLock, luaRWLockWLock, luaRWLockRefresh, luaRWLockUnlock,
+		luaSemaphoreAcquire, luaSemaphoreRefresh, luaSemaphoreRelease,
+	}
+}
+
+// LoadScripts 用 SCRIPT LOAD 预先加载所有的脚本
+// 不调用也可以正常使用，只是每个节点上第一次执行脚本的时候要多一次 EVAL。
+// redis.ClusterClient 会加载到所有的主节点上，redis.Ring 会加载到所有的分片上
+func (c *Client) LoadScripts(ctx context.Context) error {
+	if ring, ok := c.client.(*redis.Ring); ok {
+		// Ring 的 SCRIPT LOAD 只会随机发到一个分片上
+		return ring.ForEachShard(ctx, func(ctx context.Context, client *redis.Client) error {
+			return loadScripts(ctx, client)
+		})
+	}
+	return loadScripts(ctx, c.client)
+}
+
+func loadScripts(ctx context.Context, client redis.Scripter) error {
+	for _, s := range scripts() {
+		if err := s.Load(ctx, client).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_e2e_LoadScripts(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	require.NoError(t, client.LoadScripts(ctx))
+	for _, s := range scripts() {
+		exists, err := s.Exists(ctx, rdb).Result()
+		require.NoError(t, err)
+		require.Equal(t, []bool{true}, exists)
+	}
+}
+
+// BenchmarkLock_Eval 每一次都发送完整的脚本
+func BenchmarkLock_Eval(b *testing.B) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := "bench_eval_key"
+		val := strconv.Itoa(i)
+		keys := []string{key, ownerKey(key)}
+		if err := rdb.Eval(ctx, luaLockSrc, keys, val, int64(60000)).Err(); err != nil {
+			b.Fatal(err)
+		}
+		if err := rdb.Eval(ctx, luaUnlockSrc, []string{key, ownerKey(key)}, val).Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLock_EvalSha 加锁和释放锁都只发送脚本的 SHA1
+func BenchmarkLock_EvalSha(b *testing.B) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb, WithoutWatchdog())
+	ctx := context.Background()
+	if err := client.LoadScripts(ctx); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l, err := client.TryLock(ctx, "bench_evalsha_key", time.Minute)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = l.Unlock(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLock_Refresh_NoScript(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	noScript := redis.NewCmd(context.Background())
+	noScript.SetErr(redisError("NOSCRIPT No matching script. Please use EVAL."))
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(1))
+	// 服务器上没有脚本的时候退化为 EVAL
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, "value1", int64(60000)).
+			Return(noScript),
+		cmd.EXPECT().Eval(gomock.Any(), luaRefreshSrc, []string{"key1", "{key1}:owner"}, "value1", int64(60000)).
+			Return(res),
+	)
+	l := &Lock{backend: redisBackend{client: cmd}, key: "key1", value: "value1", expiration: time.Minute}
+	assert.NoError(t, l.Refresh(context.Background()))
+}
+
+func TestClient_LoadScripts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "load error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().ScriptLoad(gomock.Any(), luaLockSrc).
+					Return(redis.NewStringResult("", context.DeadlineExceeded))
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				for _, s := range scripts() {
+					cmd.EXPECT().ScriptLoad(gomock.Any(), gomock.Any()).
+						Return(redis.NewStringResult(s.Hash(), nil))
+				}
+				return cmd
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			err := client.LoadScripts(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+// redisError 模拟 Redis 服务器返回的错误
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+func (e redisError) RedisError() {}
@@ -0,0 +1,144 @@
+package redis_lock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed lua/semaphore_acquire.lua
+var luaSemaphoreAcquireSrc string
+var luaSemaphoreAcquire = redis.NewScript(luaSemaphoreAcquireSrc)
+
+//go:embed lua/semaphore_refresh.lua
+var luaSemaphoreRefreshSrc string
+var luaSemaphoreRefresh = redis.NewScript(luaSemaphoreRefreshSrc)
+
+//go:embed lua/semaphore_release.lua
+var luaSemaphoreReleaseSrc string
+var luaSemaphoreRelease = redis.NewScript(luaSemaphoreReleaseSrc)
+
+var (
+	// ErrInvalidPermits 许可数量要大于 0，并且不能超过许可总数
+	ErrInvalidPermits = errors.New("invalid permits")
+	// ErrPermitsHeld 已经持有许可了，要先 Release 才能再次获取
+	ErrPermitsHeld = errors.New("permits already held")
+)
+
+// Semaphore 基于 Redis 的计数信号量，最多允许 size 个许可同时被持有
+// 一个 Semaphore 代表一个持有者，同一时刻只能持有一份许可，
+// 不同的进程或者 goroutine 要各自创建 Semaphore。
+// 许可保存在一个 zset 里面，score 是过期时间，持有者崩溃之后许可会在过期之后自动归还，
+// 所以持有期间需要调用 Refresh 续约
+type Semaphore struct {
+	client     redis.Cmdable
+	key        string
+	size       int64
+	expiration time.Duration
+	// 持有者的标识
+	id string
+	// 当前持有的许可数量，0 代表没有持有
+	permits int64
+}
+
+// NewSemaphore 创建信号量，size 是许可总数
+// 同一个 key 的所有持有者要使用相同的 size
+func (c *Client) NewSemaphore(key string, size int64, expiration time.Duration) *Semaphore {
+	return &Semaphore{
+		client:     c.client,
+		key:        key,
+		size:       size,
+		expiration: expiration,
+		id:         uuid.New().String(),
+	}
+}
+
+// TryAcquire 尝试获取 permits 个许可，剩余的许可不够的时候返回 ErrFailedToPreemptLock
+func (s *Semaphore) TryAcquire(ctx context.Context, permits int64) error {
+	if err := s.checkPermits(permits); err != nil {
+		return err
+	}
+	ok, err := s.tryAcquire(ctx, permits)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFailedToPreemptLock
+	}
+	s.permits = permits
+	return nil
+}
+
+// Acquire 获取 permits 个许可，剩余的许可不够的时候按照 factory 创建的重试策略重试
+// timeout 是每一次获取的超时时间
+func (s *Semaphore) Acquire(ctx context.Context, permits int64,
+	timeout time.Duration, factory retry.Factory) error {
+	if err := s.checkPermits(permits); err != nil {
+		return err
+	}
+	err := retryLock(ctx, timeout, newRetryStrategy(factory), nil, func(ctx context.Context) (bool, error) {
+		return s.tryAcquire(ctx, permits)
+	})
+	if err != nil {
+		return err
+	}
+	s.permits = permits
+	return nil
+}
+
+func (s *Semaphore) checkPermits(permits int64) error {
+	if s.permits > 0 {
+		return ErrPermitsHeld
+	}
+	if permits <= 0 || permits > s.size {
+		return ErrInvalidPermits
+	}
+	return nil
+}
+
+func (s *Semaphore) tryAcquire(ctx context.Context, permits int64) (bool, error) {
+	res, err := luaSemaphoreAcquire.Run(ctx, s.client, []string{s.key},
+		s.id, permits, s.size, s.expiration.Milliseconds()).Int64()
+	return res == 1, err
+}
+
+// Refresh 续约持有的许可
+// 许可已经过期的时候返回 ErrLockNotHeld，之后可以重新获取
+func (s *Semaphore) Refresh(ctx context.Context) error {
+	if s.permits == 0 {
+		return ErrLockNotHeld
+	}
+	res, err := luaSemaphoreRefresh.Run(ctx, s.client, []string{s.key},
+		s.id, s.permits, s.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		// 已经过期了
+		s.permits = 0
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 归还持有的全部许可
+// 许可已经过期的时候返回 ErrLockNotHeld，之后可以重新获取
+func (s *Semaphore) Release(ctx context.Context) error {
+	if s.permits == 0 {
+		return ErrLockNotHeld
+	}
+	res, err := luaSemaphoreRelease.Run(ctx, s.client, []string{s.key}, s.id, s.permits).Int64()
+	if err != nil {
+		// 不确定有没有归还成功，允许再试一次
+		return err
+	}
+	s.permits = 0
+	if res != 1 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_Semaphore(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	key := "semaphore_key"
+	defer rdb.Del(ctx, key)
+
+	s1 := client.NewSemaphore(key, 3, time.Minute)
+	s2 := client.NewSemaphore(key, 3, time.Minute)
+	require.NoError(t, s1.TryAcquire(ctx, 2))
+	// 只剩一个许可
+	assert.Equal(t, ErrFailedToPreemptLock, s2.TryAcquire(ctx, 2))
+	require.NoError(t, s1.Refresh(ctx))
+
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		assert.NoError(t, s1.Release(context.Background()))
+	}()
+	// s1 归还之后就能拿到
+	require.NoError(t, s2.Acquire(ctx, 2, time.Second,
+		retry.FixedInterval(time.Millisecond*50, 20)))
+	cnt, err := rdb.ZCard(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cnt)
+
+	// 崩溃的持有者过期之后自动归还许可
+	now := time.Now().UnixMilli()
+	require.NoError(t, rdb.ZAdd(ctx, key, redis.Z{Score: float64(now - 1000), Member: "dead:1"}).Err())
+	s3 := client.NewSemaphore(key, 3, time.Minute)
+	require.NoError(t, s3.TryAcquire(ctx, 1))
+	require.NoError(t, s3.Release(ctx))
+	require.NoError(t, s2.Release(ctx))
+	assert.Equal(t, ErrLockNotHeld, s2.Release(ctx))
+}
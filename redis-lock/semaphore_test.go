@@ -0,0 +1,165 @@
+package redis_lock
+
+import (
+	"context"
+	"fmt"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestSemaphore_Acquire(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		permits int64
+		wantErr error
+	}{
+		{
+			name: "invalid permits",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismock.NewMockCmdable(ctrl)
+			},
+			permits: 4,
+			wantErr: ErrInvalidPermits,
+		},
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreAcquire.Hash(), []string{"sem1"},
+					gomock.Any(), int64(2), int64(3), int64(60000)).Return(res)
+				return cmd
+			},
+			permits: 2,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "retry and failed",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreAcquire.Hash(), []string{"sem1"},
+					gomock.Any(), int64(2), int64(3), int64(60000)).Times(3).Return(res)
+				return cmd
+			},
+			permits: 2,
+			wantErr: fmt.Errorf("超出重试限制, %w", ErrFailedToPreemptLock),
+		},
+		{
+			name: "retry and success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreAcquire.Hash(), []string{"sem1"},
+					gomock.Any(), int64(2), int64(3), int64(60000)).Return(res)
+				res2 := redis.NewCmd(context.Background())
+				res2.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreAcquire.Hash(), []string{"sem1"},
+					gomock.Any(), int64(2), int64(3), int64(60000)).Return(res2)
+				return cmd
+			},
+			permits: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			sem := client.NewSemaphore("sem1", 3, time.Minute)
+			err := sem.Acquire(context.Background(), tc.permits, time.Second,
+				retry.FixedInterval(time.Millisecond, 2))
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				assert.Equal(t, int64(0), sem.permits)
+				return
+			}
+			assert.Equal(t, tc.permits, sem.permits)
+			// 持有期间不能再次获取
+			assert.Equal(t, ErrPermitsHeld, sem.TryAcquire(context.Background(), 1))
+		})
+	}
+}
+
+func TestSemaphore_Release(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mock        func(ctrl *gomock.Controller) redis.Cmdable
+		permits     int64
+		wantErr     error
+		wantPermits int64
+	}{
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismock.NewMockCmdable(ctrl)
+			},
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreRelease.Hash(), []string{"sem1"},
+					"id1", int64(2)).Return(res)
+				return cmd
+			},
+			permits:     2,
+			wantErr:     context.DeadlineExceeded,
+			wantPermits: 2,
+		},
+		{
+			name: "expired",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreRelease.Hash(), []string{"sem1"},
+					"id1", int64(2)).Return(res)
+				return cmd
+			},
+			permits: 2,
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaSemaphoreRelease.Hash(), []string{"sem1"},
+					"id1", int64(2)).Return(res)
+				return cmd
+			},
+			permits: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			sem := &Semaphore{
+				client:     tc.mock(ctrl),
+				key:        "sem1",
+				size:       3,
+				expiration: time.Minute,
+				id:         "id1",
+				permits:    tc.permits,
+			}
+			err := sem.Release(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantPermits, sem.permits)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package redis_lock
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+//go:embed lua/transfer.lua
+var luaTransferSrc string
+var luaTransfer = redis.NewScript(luaTransferSrc)
+
+var (
+	// ErrNoTokenSecret 没有用 WithTokenSecret 设置密钥，不能导出或者使用 Token
+	ErrNoTokenSecret = errors.New("token secret not configured")
+	// ErrInvalidToken Token 格式不对或者签名不对
+	ErrInvalidToken = errors.New("invalid lock token")
+)
+
+// WithTokenSecret 签名 Lock.Token 的密钥
+// 要在不同的进程之间传递锁，所有进程要使用相同的密钥
+func WithTokenSecret(secret []byte) ClientOption {
+	return func(c *Client) {
+		c.tokenSecret = secret
+	}
+}
+
+// lockToken Token 里面的内容
+type lockToken struct {
+	Key          string `json:"k"`
+	Value        string `json:"v"`
+	Expiration   int64  `json:"e"`
+	FencingToken int64  `json:"f"`
+}
+
+// Token 导出一个签了名的句柄，Client.Resume 可以用它重建这把锁
+// 拿到 Token 的人和自己共同持有这把锁，可以续约也可以释放，
+// 比如把 Token 持久化下来，进程重启之后用 Resume 找回锁。
+// 要把锁交给别的进程，并且自己不再持有，用 Client.Transfer
+func (l *Lock) Token() (string, error) {
+	return signToken(l.tokenSecret, lockToken{
+		Key:          l.key,
+		Value:        l.value,
+		Expiration:   l.expiration.Milliseconds(),
+		FencingToken: l.fencingToken,
+	})
+}
+
+// Resume 用 Lock.Token 导出的句柄重建锁，锁已经不是 Token 的持有者的时候返回 ErrLockNotHeld
+// 重建的锁和 Client 创建的其它锁一样，开启了看门狗的时候会自动续约
+func (c *Client) Resume(ctx context.Context, token string) (*Lock, error) {
+	t, err := parseToken(c.tokenSecret, token)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	ttl, err := luaTTL.Run(ctx, c.client, []string{t.Key}, t.Value).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if ttl == -2 {
+		return nil, ErrLockNotHeld
+	}
+	expiration := time.Duration(t.Expiration) * time.Millisecond
+	if ttl >= 0 {
+		// 用剩余的过期时间估算本地的过期时间
+		start = start.Add(time.Duration(ttl)*time.Millisecond - expiration)
+	}
+	return c.newLock(t.Key, t.Value, expiration, t.FencingToken, start, nil), nil
+}
+
+// Transfer 把锁原子地转移给 newValue 代表的新持有者，返回新持有者的 Token
+// 锁的 value 在一条命令里面被替换掉，中间不会有第三个人抢到锁，
+// 过期时间会重置为完整的过期时间，新持有者要在过期之前用 Resume 接手。
+// 转移成功之后 l 就失效了，看门狗会停止，续约和释放都会失败，Observer 会收到 l 的 OnRelease。
+// 持有者信息换成 owner，为空的字段和 WithOwnerMetadata 一样使用 Client 的默认值，fencing token 不变。
+// 返回网络错误的时候不确定有没有转移成功，可以用同样的 newValue 再试一次
+func (c *Client) Transfer(ctx context.Context, l *Lock, newValue string, owner Owner) (string, error) {
+	if len(c.tokenSecret) == 0 {
+		return "", ErrNoTokenSecret
+	}
+	if newValue == "" {
+		return "", fmt.Errorf("%w: 锁的 value 不能为空", ErrInvalidLockOption)
+	}
+	if l.released.Load() {
+		return "", ErrLockNotHeld
+	}
+	// 先暂停看门狗，免得它在转移之后用旧的 value 续约失败，报告锁丢失。
+	// 转移成功之前锁还是自己的，不能取消 l.Context()
+	l.pauseRefresh()
+	args := append([]any{l.value, newValue, l.expiration.Milliseconds()}, ownerArgs(mergeOwner(c.owner, owner))...)
+	res, err := luaTransfer.Run(ctx, c.client, []string{l.key, ownerKey(l.key)}, args...).Int64()
+	if err != nil {
+		l.resumeRefresh()
+		return "", err
+	}
+	if res != 1 {
+		// 锁已经不是自己的了，让看门狗去发现并报告锁丢失
+		l.resumeRefresh()
+		return "", ErrLockNotHeld
+	}
+	l.released.Store(true)
+	l.stop(nil)
+	l.releaseLocal()
+	if l.observer != nil {
+		// 对原来的持有者来说，转移出去就是释放了
+		l.observer.OnRelease(ctx, ReleaseEvent{Key: l.key, Held: time.Since(l.acquiredAt)})
+	}
+	return signToken(c.tokenSecret, lockToken{
+		Key:          l.key,
+		Value:        newValue,
+		Expiration:   l.expiration.Milliseconds(),
+		FencingToken: l.fencingToken,
+	})
+}
+
+// signToken Token 的格式是 base64(json).base64(HMAC-SHA256)
+func signToken(secret []byte, t lockToken) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrNoTokenSecret
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, payload)), nil
+}
+
+func parseToken(secret []byte, token string) (lockToken, error) {
+	var t lockToken
+	if len(secret) == 0 {
+		return t, ErrNoTokenSecret
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return t, ErrInvalidToken
+	}
+	rawSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(rawSig, tokenSignature(secret, payload)) {
+		return t, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return t, ErrInvalidToken
+	}
+	if err = json.Unmarshal(data, &t); err != nil || t.Key == "" || t.Value == "" || t.Expiration <= 0 {
+		return t, ErrInvalidToken
+	}
+	return t, nil
+}
+
+func tokenSignature(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,59 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_Transfer(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	secret := []byte("e2e secret")
+	// 模拟两个进程
+	clientA := NewClient(rdb, WithTokenSecret(secret), WithoutWatchdog())
+	clientB := NewClient(rdb, WithTokenSecret(secret), WithoutWatchdog())
+
+	l, err := clientA.TryLock(ctx, "transfer_key", time.Minute)
+	require.NoError(t, err)
+
+	// 持久化 Token 之后找回锁
+	token, err := l.Token()
+	require.NoError(t, err)
+	resumed, err := clientB.Resume(ctx, token)
+	require.NoError(t, err)
+	require.NoError(t, resumed.Refresh(ctx))
+	assert.Equal(t, l.FencingToken(), resumed.FencingToken())
+
+	token, err = clientA.Transfer(ctx, l, "process-b", Owner{Hostname: "host-b", PID: 42, Label: "b"})
+	require.NoError(t, err)
+	val, err := rdb.Get(ctx, "transfer_key").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "process-b", val)
+	// 持有者信息也换成了新的持有者的
+	info, err := clientB.Inspect(ctx, "transfer_key")
+	require.NoError(t, err)
+	assert.Equal(t, "host-b", info.Owner.Hostname)
+	assert.Equal(t, 42, info.Owner.PID)
+	assert.Equal(t, "b", info.Owner.Label)
+	// 转移之后别人抢不到，旧的持有者也不能再续约
+	_, err = clientA.TryLock(ctx, "transfer_key", time.Minute)
+	assert.Equal(t, ErrFailedToPreemptLock, err)
+	assert.Equal(t, ErrLockNotHeld, l.Refresh(ctx))
+	assert.Equal(t, ErrLockNotHeld, resumed.Refresh(ctx))
+
+	l2, err := clientB.Resume(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, l.FencingToken(), l2.FencingToken())
+	require.NoError(t, l2.Unlock(ctx))
+	_, err = clientB.Resume(ctx, token)
+	assert.Equal(t, ErrLockNotHeld, err)
+}
@@ -0,0 +1,204 @@
+package redis_lock
+
+import (
+	"context"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLock_Token(t *testing.T) {
+	secret := []byte("secret")
+	l := &Lock{key: "key1", value: "value1", expiration: time.Minute, fencingToken: 3, tokenSecret: secret}
+	token, err := l.Token()
+	require.NoError(t, err)
+
+	got, err := parseToken(secret, token)
+	require.NoError(t, err)
+	assert.Equal(t, lockToken{Key: "key1", Value: "value1", Expiration: 60000, FencingToken: 3}, got)
+
+	_, err = parseToken([]byte("other secret"), token)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = parseToken(nil, token)
+	assert.Equal(t, ErrNoTokenSecret, err)
+	// 改了内容签名就对不上了
+	forged, err := signToken([]byte("other secret"), lockToken{Key: "key1", Value: "value2", Expiration: 60000})
+	require.NoError(t, err)
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	_, err = parseToken(secret, forgedPayload+"."+sig)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = parseToken(secret, "abc")
+	assert.Equal(t, ErrInvalidToken, err)
+
+	l.tokenSecret = nil
+	_, err = l.Token()
+	assert.Equal(t, ErrNoTokenSecret, err)
+}
+
+func TestClient_Resume(t *testing.T) {
+	secret := []byte("secret")
+	token, err := signToken(secret, lockToken{Key: "key1", Value: "value1", Expiration: 60000, FencingToken: 3})
+	require.NoError(t, err)
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) redis.Cmdable
+		token string
+
+		wantErr error
+		wantTTL time.Duration
+	}{
+		{
+			name: "invalid token",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismock.NewMockCmdable(ctrl)
+			},
+			token:   token + "x",
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(-2))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaTTL.Hash(), []string{"key1"}, "value1").Return(res)
+				return cmd
+			},
+			token:   token,
+			wantErr: ErrLockNotHeld,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(20000))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaTTL.Hash(), []string{"key1"}, "value1").Return(res)
+				return cmd
+			},
+			token:   token,
+			wantTTL: time.Second * 20,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl), WithTokenSecret(secret), WithoutWatchdog())
+			l, err := client.Resume(context.Background(), tc.token)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "key1", l.key)
+			assert.Equal(t, "value1", l.value)
+			assert.Equal(t, time.Minute, l.expiration)
+			assert.Equal(t, int64(3), l.FencingToken())
+			// 本地的过期时间按照剩余的过期时间估算
+			assert.WithinDuration(t, time.Now().Add(tc.wantTTL), l.ValidUntil(), time.Millisecond*100)
+		})
+	}
+}
+
+func TestClient_Transfer(t *testing.T) {
+	secret := []byte("secret")
+	// 持有者信息换成新的持有者的，没有设置的 PID 使用 Client 的默认值
+	owner := Owner{Hostname: "host-b", Label: "worker"}
+	transferArgs := []any{"value1", "value2", int64(60000),
+		ownerFieldHostname, "host-b", ownerFieldPID, os.Getpid(),
+		ownerFieldAcquiredAt, gomock.Any(), ownerFieldLabel, "worker"}
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) redis.Cmdable
+		secret   []byte
+		newValue string
+
+		wantErr error
+	}{
+		{
+			name: "no secret",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismock.NewMockCmdable(ctrl)
+			},
+			newValue: "value2",
+			wantErr:  ErrNoTokenSecret,
+		},
+		{
+			name: "not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaTransfer.Hash(), []string{"key1", "{key1}:owner"},
+					transferArgs...).Return(res)
+				return cmd
+			},
+			secret:   secret,
+			newValue: "value2",
+			wantErr:  ErrLockNotHeld,
+		},
+		{
+			name: "network error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaTransfer.Hash(), []string{"key1", "{key1}:owner"},
+					transferArgs...).Return(res)
+				return cmd
+			},
+			secret:   secret,
+			newValue: "value2",
+			wantErr:  context.DeadlineExceeded,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaTransfer.Hash(), []string{"key1", "{key1}:owner"},
+					transferArgs...).Return(res)
+				return cmd
+			},
+			secret:   secret,
+			newValue: "value2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := tc.mock(ctrl)
+			observer := &recordObserver{}
+			client := NewClient(cmd, WithTokenSecret(tc.secret), WithObserver(observer), WithoutWatchdog())
+			l := client.newLock("key1", "value1", time.Minute, 3, time.Now(), nil)
+			token, err := client.Transfer(context.Background(), l, tc.newValue, owner)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				// 没有转移成功，锁依旧有效，看门狗也恢复了
+				assert.NoError(t, l.Context().Err())
+				assert.False(t, l.paused)
+				assert.Empty(t, observer.releases)
+				return
+			}
+			// 对原来的持有者来说锁已经释放了
+			require.Len(t, observer.releases, 1)
+			assert.Equal(t, "key1", observer.releases[0].Key)
+			assert.NoError(t, observer.releases[0].Err)
+			got, err := parseToken(secret, token)
+			require.NoError(t, err)
+			assert.Equal(t, lockToken{Key: "key1", Value: "value2", Expiration: 60000, FencingToken: 3}, got)
+			// 旧的锁失效了，释放的时候不会访问 Redis
+			assert.Error(t, l.Context().Err())
+			assert.Equal(t, ErrLockNotHeld, l.Unlock(context.Background()))
+		})
+	}
+}
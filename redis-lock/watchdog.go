@@ -0,0 +1,148 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Jared-lu/GXT/retry"
+	"time"
+)
+
+const (
+	// 续约失败之后，重试续约的最短间隔
+	minWatchdogRetryInterval = 10 * time.Millisecond
+)
+
+type watchdogConfig struct {
+	enabled bool
+	// 多久续约一次，<= 0 的时候使用过期时间的三分之一
+	interval time.Duration
+	// 每一次续约的超时时间，<= 0 的时候和 interval 相同
+	timeout time.Duration
+}
+
+// WithWatchdog 设置看门狗的续约间隔以及每一次续约的超时时间
+// 默认情况下每隔过期时间的三分之一续约一次，超时时间和续约间隔相同
+func WithWatchdog(interval time.Duration, timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.watchdog = watchdogConfig{enabled: true, interval: interval, timeout: timeout}
+	}
+}
+
+// WithoutWatchdog 关闭看门狗，加锁成功之后不会自动续约，
+// 需要用户自己调用 Refresh 或者 AutoRefresh
+func WithoutWatchdog() ClientOption {
+	return func(c *Client) {
+		c.watchdog = watchdogConfig{}
+	}
+}
+
+// newLock 创建锁，开启了看门狗的时候会在后台自动续约
+// start 是发出加锁请求的时间，release 不为 nil 的时候会在锁释放或者丢失之后调用
+func (c *Client) newLock(key string, value string, expiration time.Duration,
+	fencingToken int64, start time.Time, release func()) *Lock {
+	l := newLock(redisBackend{client: c.client, publish: c.publishFlag()}, key, value, expiration, start)
+	if c.fencing {
+		l.fencingToken = fencingToken
+	}
+	l.tokenSecret = c.tokenSecret
+	l.release = release
+	l.observer = c.observer
+	c.watchdog.start(l)
+	return l
+}
+
+func (cfg watchdogConfig) newLock(backend lockBackend, key string, value string,
+	expiration time.Duration, fencingToken int64, start time.Time) *Lock {
+	l := newLock(backend, key, value, expiration, start)
+	l.fencingToken = fencingToken
+	cfg.start(l)
+	return l
+}
+
+func (cfg watchdogConfig) start(l *Lock) {
+	if cfg.enabled {
+		l.startWatchdog(cfg.interval, cfg.timeout)
+	}
+}
+
+// startWatchdog 启动看门狗，重复调用只会启动一次
+func (l *Lock) startWatchdog(interval time.Duration, timeout time.Duration) {
+	if !l.watching.CompareAndSwap(false, true) {
+		return
+	}
+	if interval <= 0 {
+		interval = l.expiration / 3
+	}
+	if interval <= 0 {
+		interval = minWatchdogRetryInterval
+	}
+	if timeout <= 0 {
+		timeout = interval
+	}
+	go l.watch(interval, timeout)
+}
+
+// watch 定时续约
+// 续约超时或者网络错误的时候会尽快重试，直到锁在 Redis 上已经过期；
+// 锁已经不是自己的，或者一直续约失败直到过期，都认为锁丢失了
+func (l *Lock) watch(interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	retryInterval := max(interval/10, minWatchdogRetryInterval)
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.unlockChan:
+			return
+		}
+		// 续约失败之后一直重试到锁过期，释放锁的时候 l.ctx 会被取消
+		ctx, cancel := context.WithDeadline(l.ctx, time.Unix(0, l.leaseDeadline.Load()))
+		err := retry.Do(ctx, retry.FixedInterval(retryInterval, 0)(), func(ctx context.Context) error {
+			l.refreshMu.Lock()
+			defer l.refreshMu.Unlock()
+			if l.paused {
+				return nil
+			}
+			err := l.refreshOnce(ctx, timeout)
+			if errors.Is(err, ErrLockNotHeld) {
+				return retry.Permanent(err)
+			}
+			return err
+		})
+		cancel()
+		switch {
+		case err == nil:
+		case l.ctx.Err() != nil:
+			// 已经释放了
+			return
+		case errors.Is(err, ErrLockNotHeld):
+			// 锁已经不是自己的了，Unlock 不需要再访问 Redis
+			l.released.Store(true)
+			l.stop(fmt.Errorf("%w: %w", ErrLockLost, err))
+			return
+		default:
+			l.stop(fmt.Errorf("%w: 续约失败直到锁过期: %w", ErrLockLost, err))
+			return
+		}
+	}
+}
+
+// pauseRefresh 暂停看门狗，返回之后不会再有正在进行的续约
+func (l *Lock) pauseRefresh() {
+	l.refreshMu.Lock()
+	l.paused = true
+	l.refreshMu.Unlock()
+}
+
+func (l *Lock) resumeRefresh() {
+	l.refreshMu.Lock()
+	l.paused = false
+	l.refreshMu.Unlock()
+}
+
+func (l *Lock) refreshOnce(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return l.Refresh(ctx)
+}
@@ -0,0 +1,120 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLock_Watchdog(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+		// 加锁时的过期时间，看门狗每隔三分之一续约一次
+		expiration time.Duration
+		wantErr    error
+	}{
+		{
+			name: "lock not held",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					Return(res)
+				return cmd
+			},
+			expiration: time.Millisecond * 300,
+			wantErr:    ErrLockNotHeld,
+		},
+		{
+			name: "refresh error until expired",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					AnyTimes().Return(res)
+				return cmd
+			},
+			expiration: time.Millisecond * 300,
+			wantErr:    context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl))
+			l := client.newLock("key1", "value1", tc.expiration, 1, time.Now(), nil)
+
+			select {
+			case err := <-l.Lost():
+				assert.True(t, errors.Is(err, ErrLockLost))
+				assert.True(t, errors.Is(err, tc.wantErr))
+			case <-time.After(time.Second * 3):
+				t.Fatal("没有感知到锁丢失")
+			}
+			<-l.Context().Done()
+			assert.True(t, errors.Is(context.Cause(l.Context()), ErrLockLost))
+			// 丢失之后 channel 会被关闭
+			_, ok := <-l.Lost()
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestLock_WatchdogRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	refreshRes := redis.NewCmd(context.Background())
+	refreshRes.SetVal(int64(1))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+		MinTimes(2).Return(refreshRes)
+	unlockRes := redis.NewCmd(context.Background())
+	unlockRes.SetVal(int64(1))
+	cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+		Return(unlockRes)
+
+	client := NewClient(cmd, WithWatchdog(time.Millisecond*50, time.Second))
+	l := client.newLock("key1", "value1", time.Second, 1, time.Now(), nil)
+	time.Sleep(time.Millisecond * 180)
+	require.NoError(t, l.Context().Err())
+
+	require.NoError(t, l.Unlock(context.Background()))
+	// 主动释放不算丢失
+	_, ok := <-l.Lost()
+	assert.False(t, ok)
+	assert.Equal(t, context.Canceled, l.Context().Err())
+	assert.Equal(t, context.Canceled, context.Cause(l.Context()))
+	// 重复释放不会再访问 Redis
+	assert.Equal(t, ErrLockNotHeld, l.Unlock(context.Background()))
+}
+
+func TestLock_UnlockRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismock.NewMockCmdable(ctrl)
+	errRes := redis.NewCmd(context.Background())
+	errRes.SetErr(context.DeadlineExceeded)
+	okRes := redis.NewCmd(context.Background())
+	okRes.SetVal(int64(1))
+	gomock.InOrder(
+		cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).Return(errRes),
+		cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).Return(okRes),
+	)
+
+	client := NewClient(cmd, WithoutWatchdog())
+	l := client.newLock("key1", "value1", time.Minute, 1, time.Now(), nil)
+	// 网络错误，不确定有没有释放成功，可以再试一次
+	assert.Equal(t, context.DeadlineExceeded, l.Unlock(context.Background()))
+	assert.NoError(t, l.Unlock(context.Background()))
+	assert.Equal(t, ErrLockNotHeld, l.Unlock(context.Background()))
+}
@@ -0,0 +1,75 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Jared-lu/GXT/retry"
+	"time"
+)
+
+var (
+	// ErrAcquireFailed WithLock 没有拿到锁，fn 没有执行
+	ErrAcquireFailed = errors.New("acquire lock failed")
+	// ErrFnFailed WithLock 里面 fn 返回了 error，锁一直都持有
+	ErrFnFailed = errors.New("fn failed")
+)
+
+const defaultUnlockTimeout = time.Second
+
+// WithLockOptions WithLock 的加锁参数
+type WithLockOptions struct {
+	// 锁的过期时间，看门狗会一直续约
+	Expiration time.Duration
+	// 每一次加锁和释放锁的超时时间，<= 0 的时候加锁不设置超时
+	Timeout time.Duration
+	// 加锁的重试策略，每次调用 WithLock 都会创建一个新的，为 nil 的时候只尝试一次
+	Retry retry.Factory
+}
+
+// WithLock 拿到锁之后执行 fn，fn 返回之后一定会释放锁
+// fn 执行期间看门狗会一直续约，锁丢失的时候传给 fn 的 ctx 会被取消。
+// 返回的 error：
+//   - 没拿到锁，包装 ErrAcquireFailed，fn 不会执行
+//   - fn 执行期间锁丢失了，包装 ErrLockLost，同时包装 fn 返回的 error
+//   - fn 返回了 error，包装 ErrFnFailed
+//   - 释放锁失败，原样返回释放锁的 error，锁会在过期之后自动释放
+func (c *Client) WithLock(ctx context.Context, key string, opts WithLockOptions,
+	fn func(ctx context.Context) error) error {
+	l, err := c.Lock(ctx, key, opts.Expiration, opts.Timeout, opts.Retry)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAcquireFailed, err)
+	}
+	// 关闭了看门狗也要续约，重复调用只会启动一次
+	l.startWatchdog(0, 0)
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(l.Context(), func() {
+		cancel(context.Cause(l.Context()))
+	})
+	defer stop()
+	fnErr := fn(fnCtx)
+
+	// 要在释放锁之前检查，释放锁也会取消 l.Context()
+	lostErr := context.Cause(l.Context())
+	unlockTimeout := opts.Timeout
+	if unlockTimeout <= 0 {
+		unlockTimeout = defaultUnlockTimeout
+	}
+	unlockCtx, unlockCancel := context.WithTimeout(context.WithoutCancel(ctx), unlockTimeout)
+	defer unlockCancel()
+	unlockErr := l.Unlock(unlockCtx)
+
+	switch {
+	case lostErr != nil:
+		if fnErr != nil {
+			return fmt.Errorf("%w, fn: %w", lostErr, fnErr)
+		}
+		return lostErr
+	case fnErr != nil:
+		return fmt.Errorf("%w: %w", ErrFnFailed, fnErr)
+	default:
+		return unlockErr
+	}
+}
@@ -0,0 +1,37 @@
+//go:build e2e
+
+package redis_lock
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_e2e_WithLock(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	client := NewClient(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	opts := WithLockOptions{Expiration: time.Second * 10, Timeout: time.Second}
+
+	err := client.WithLock(ctx, "with_lock_key", opts, func(ctx context.Context) error {
+		// 执行期间别人拿不到锁
+		err := client.WithLock(ctx, "with_lock_key", opts, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrAcquireFailed)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// 执行完之后锁已经释放
+	cnt, err := rdb.Exists(ctx, "with_lock_key").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+}
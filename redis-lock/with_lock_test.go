@@ -0,0 +1,140 @@
+package redis_lock
+
+import (
+	"context"
+	"errors"
+	redismock "github.com/Jared-lu/GXT/redis-lock/mock/redis"
+	"github.com/Jared-lu/GXT/retry"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestClient_WithLock(t *testing.T) {
+	errBiz := errors.New("biz error")
+	evalRes := func(val int64) *redis.Cmd {
+		res := redis.NewCmd(context.Background())
+		res.SetVal(val)
+		return res
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) redis.Cmdable
+		retry retry.Factory
+		fn    func(ctx context.Context) error
+
+		wantErrs  []error
+		wantFnRun bool
+	}{
+		{
+			name: "acquire failed",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+					Return(evalRes(0))
+				return cmd
+			},
+			fn: func(ctx context.Context) error {
+				return nil
+			},
+			wantErrs: []error{ErrAcquireFailed, ErrFailedToPreemptLock},
+		},
+		{
+			name: "retry and success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				gomock.InOrder(
+					cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+						Return(evalRes(0)),
+					cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+						Return(evalRes(1)),
+				)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					Return(evalRes(1))
+				return cmd
+			},
+			retry: retry.FixedInterval(time.Millisecond, 1),
+			fn: func(ctx context.Context) error {
+				return nil
+			},
+			wantFnRun: true,
+		},
+		{
+			name: "fn failed",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+					Return(evalRes(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					Return(evalRes(1))
+				return cmd
+			},
+			fn: func(ctx context.Context) error {
+				return errBiz
+			},
+			wantErrs:  []error{ErrFnFailed, errBiz},
+			wantFnRun: true,
+		},
+		{
+			name: "lock lost",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+					Return(evalRes(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaRefresh.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					Return(evalRes(0))
+				return cmd
+			},
+			fn: func(ctx context.Context) error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second * 3):
+					return nil
+				}
+			},
+			wantErrs:  []error{ErrLockLost, ErrLockNotHeld, context.Canceled},
+			wantFnRun: true,
+		},
+		{
+			name: "success",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismock.NewMockCmdable(ctrl)
+				cmd.EXPECT().EvalSha(gomock.Any(), luaLock.Hash(), []string{"key1", "{key1}:owner", "{key1}:fencing"}, gomock.Any()).
+					Return(evalRes(1))
+				cmd.EXPECT().EvalSha(gomock.Any(), luaUnlock.Hash(), []string{"key1", "{key1}:owner"}, gomock.Any()).
+					Return(evalRes(1))
+				return cmd
+			},
+			fn: func(ctx context.Context) error {
+				return nil
+			},
+			wantFnRun: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := NewClient(tc.mock(ctrl), WithWatchdog(time.Millisecond*50, time.Second))
+			fnRun := false
+			err := client.WithLock(context.Background(), "key1", WithLockOptions{
+				Expiration: time.Second,
+				Timeout:    time.Second,
+				Retry:      tc.retry,
+			}, func(ctx context.Context) error {
+				fnRun = true
+				return tc.fn(ctx)
+			})
+			assert.Equal(t, tc.wantFnRun, fnRun)
+			if len(tc.wantErrs) == 0 {
+				assert.NoError(t, err)
+			}
+			for _, wantErr := range tc.wantErrs {
+				assert.ErrorIs(t, err, wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMaxRetries 重试策略不允许再重试了，会同时包装最后一次的 error
+var ErrMaxRetries = errors.New("retry: 超出重试限制")
+
+// Classifier 判断一个 error 要不要重试
+type Classifier func(err error) bool
+
+// Permanent 标记 err 不需要重试，Do 会立刻返回 err
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// IsRetryable 默认的分类，除了 Permanent 标记过的 error 都要重试
+func IsRetryable(err error) bool {
+	var p *permanentError
+	return !errors.As(err, &p)
+}
+
+// Do 执行 fn，失败的时候按照 s 重试
+// 直到 fn 成功、s 不再重试、fn 返回了 Permanent 标记的 error 或者 ctx 过期。
+// 返回的 error：
+//   - fn 返回了 Permanent 标记的 error，返回被标记的 error
+//   - s 不再重试，包装 ErrMaxRetries 和最后一次的 error
+//   - ctx 过期，包装 ctx.Err() 和最后一次的 error
+func Do(ctx context.Context, s Strategy, fn func(ctx context.Context) error) error {
+	return DoWithClassifier(ctx, s, IsRetryable, fn)
+}
+
+// DoWithClassifier 和 Do 一样，但是由 classifier 判断 error 要不要重试
+// classifier 返回 false 的时候立刻返回 fn 返回的 error，Permanent 标记过的 error 依旧不会重试
+func DoWithClassifier(ctx context.Context, s Strategy, classifier Classifier,
+	fn func(ctx context.Context) error) error {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) || !classifier(err) {
+			if p, ok := err.(*permanentError); ok {
+				return p.err
+			}
+			return err
+		}
+		interval, ok := s.Next()
+		if !ok {
+			return fmt.Errorf("%w: %w", ErrMaxRetries, err)
+		}
+		if timer == nil {
+			timer = time.NewTimer(interval)
+		} else {
+			timer.Reset(interval)
+		}
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%w, 最后一次的 error: %w", ctx.Err(), err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	errBiz := errors.New("biz error")
+	testCases := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		strategy Strategy
+		// 第几次调用返回什么
+		results []error
+
+		wantErrs []error
+		wantCnt  int
+	}{
+		{
+			name:     "success",
+			strategy: FixedInterval(time.Millisecond, 3)(),
+			results:  []error{nil},
+			wantCnt:  1,
+		},
+		{
+			name:     "success after retry",
+			strategy: FixedInterval(time.Millisecond, 3)(),
+			results:  []error{errBiz, errBiz, nil},
+			wantCnt:  3,
+		},
+		{
+			name:     "max retries",
+			strategy: FixedInterval(time.Millisecond, 2)(),
+			results:  []error{errBiz, errBiz, errBiz},
+			wantErrs: []error{ErrMaxRetries, errBiz},
+			wantCnt:  3,
+		},
+		{
+			name:     "permanent",
+			strategy: FixedInterval(time.Millisecond, 3)(),
+			results:  []error{errBiz, Permanent(errBiz)},
+			wantErrs: []error{errBiz},
+			wantCnt:  2,
+		},
+		{
+			name: "context timeout",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Millisecond*50)
+			},
+			strategy: FixedInterval(time.Second, 3)(),
+			results:  []error{errBiz},
+			wantErrs: []error{context.DeadlineExceeded, errBiz},
+			wantCnt:  1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tc.ctx != nil {
+				ctx, cancel = tc.ctx()
+			}
+			defer cancel()
+			cnt := 0
+			err := Do(ctx, tc.strategy, func(ctx context.Context) error {
+				err := tc.results[cnt]
+				cnt++
+				return err
+			})
+			assert.Equal(t, tc.wantCnt, cnt)
+			if len(tc.wantErrs) == 0 {
+				assert.NoError(t, err)
+			}
+			for _, wantErr := range tc.wantErrs {
+				assert.ErrorIs(t, err, wantErr)
+			}
+		})
+	}
+}
+
+func TestDoWithClassifier(t *testing.T) {
+	errRetryable := errors.New("retryable")
+	errFatal := errors.New("fatal")
+	cnt := 0
+	err := DoWithClassifier(context.Background(), FixedInterval(time.Millisecond, 5)(),
+		func(err error) bool {
+			return errors.Is(err, errRetryable)
+		}, func(ctx context.Context) error {
+			cnt++
+			if cnt < 3 {
+				return errRetryable
+			}
+			return errFatal
+		})
+	assert.Equal(t, errFatal, err)
+	assert.Equal(t, 3, cnt)
+}
+
+func ExampleDo() {
+	// 工厂可以共享，策略每次操作都要重新创建
+	factory := MaxElapsed(DecorrelatedJitter(time.Millisecond*10, time.Second, 5), time.Second*3)
+	_ = Do(context.Background(), factory(), func(ctx context.Context) error {
+		// 调用下游
+		return nil
+	})
+}
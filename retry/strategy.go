@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Strategy 一次操作的重试策略
+// Strategy 是有状态的，记录了已经重试的次数，不能在多次操作之间共享，
+// 每次操作都要用 Factory 创建一个新的
+type Strategy interface {
+	// Next
+	// 第一个返回值：重试的间隔
+	// 第二个返回值：要不要继续重试
+	Next() (time.Duration, bool)
+}
+
+// Factory 创建重试策略，可以在多次操作之间共享
+type Factory func() Strategy
+
+// FixedInterval 固定间隔重试，最多重试 maxRetries 次，maxRetries <= 0 的时候不限次数
+func FixedInterval(interval time.Duration, maxRetries int) Factory {
+	return func() Strategy {
+		return &fixedInterval{interval: interval, maxRetries: maxRetries}
+	}
+}
+
+type fixedInterval struct {
+	interval   time.Duration
+	maxRetries int
+	cnt        int
+}
+
+func (f *fixedInterval) Next() (time.Duration, bool) {
+	if f.maxRetries > 0 && f.cnt >= f.maxRetries {
+		return 0, false
+	}
+	f.cnt++
+	return f.interval, true
+}
+
+// ExponentialBackoff 指数退避，重试间隔从 initial 开始每次翻倍，最大不超过 maxInterval
+// maxInterval <= 0 的时候不设上限，最多重试 maxRetries 次，maxRetries <= 0 的时候不限次数
+func ExponentialBackoff(initial time.Duration, maxInterval time.Duration, maxRetries int) Factory {
+	maxInterval = intervalCap(maxInterval)
+	return func() Strategy {
+		return &exponentialBackoff{initial: initial, maxInterval: maxInterval, maxRetries: maxRetries}
+	}
+}
+
+type exponentialBackoff struct {
+	initial     time.Duration
+	maxInterval time.Duration
+	maxRetries  int
+	cnt         int
+	interval    time.Duration
+}
+
+func (e *exponentialBackoff) Next() (time.Duration, bool) {
+	if e.maxRetries > 0 && e.cnt >= e.maxRetries {
+		return 0, false
+	}
+	e.cnt++
+	if e.interval == 0 {
+		e.interval = e.initial
+	} else {
+		// 防止溢出
+		e.interval = min(e.interval, e.maxInterval/2) * 2
+	}
+	e.interval = min(e.interval, e.maxInterval)
+	return e.interval, true
+}
+
+// DecorrelatedJitter 带抖动的指数退避，下一次的间隔是 [base, 上一次间隔 * 3) 里面的随机值，最大不超过 maxInterval
+// 多个客户端同时失败的时候不会在同一时刻一起重试。
+// maxInterval <= 0 的时候不设上限，最多重试 maxRetries 次，maxRetries <= 0 的时候不限次数
+func DecorrelatedJitter(base time.Duration, maxInterval time.Duration, maxRetries int) Factory {
+	maxInterval = intervalCap(maxInterval)
+	return func() Strategy {
+		return &decorrelatedJitter{base: base, maxInterval: maxInterval, maxRetries: maxRetries}
+	}
+}
+
+type decorrelatedJitter struct {
+	base        time.Duration
+	maxInterval time.Duration
+	maxRetries  int
+	cnt         int
+	interval    time.Duration
+}
+
+func (d *decorrelatedJitter) Next() (time.Duration, bool) {
+	if d.maxRetries > 0 && d.cnt >= d.maxRetries {
+		return 0, false
+	}
+	d.cnt++
+	upper := max(d.interval, d.base)
+	// 防止溢出
+	upper = min(upper, d.maxInterval/3) * 3
+	if upper > d.base {
+		d.interval = d.base + time.Duration(rand.Int63n(int64(upper-d.base)))
+	} else {
+		d.interval = d.base
+	}
+	d.interval = min(d.interval, d.maxInterval)
+	return d.interval, true
+}
+
+// intervalCap maxInterval <= 0 代表不设上限
+// 直接拿来 min 的话每一次的间隔都是 0，调用方会不停地重试
+func intervalCap(maxInterval time.Duration) time.Duration {
+	if maxInterval <= 0 {
+		return math.MaxInt64
+	}
+	return maxInterval
+}
+
+// MaxElapsed 限制总的重试时间，从创建策略开始计算，
+// 等到下一次重试的时候会超过 maxElapsed 就不再重试
+func MaxElapsed(f Factory, maxElapsed time.Duration) Factory {
+	return func() Strategy {
+		return &maxElapsedStrategy{Strategy: f(), deadline: time.Now().Add(maxElapsed)}
+	}
+}
+
+type maxElapsedStrategy struct {
+	Strategy
+	deadline time.Time
+}
+
+func (m *maxElapsedStrategy) Next() (time.Duration, bool) {
+	interval, ok := m.Strategy.Next()
+	if !ok || time.Now().Add(interval).After(m.deadline) {
+		return 0, false
+	}
+	return interval, true
+}
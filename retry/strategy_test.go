@@ -0,0 +1,139 @@
+package retry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFixedInterval(t *testing.T) {
+	f := FixedInterval(time.Second, 2)
+	s := f()
+	for i := 0; i < 2; i++ {
+		interval, ok := s.Next()
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, interval)
+	}
+	_, ok := s.Next()
+	assert.False(t, ok)
+
+	// 每次创建的都是新的策略
+	interval, ok := f().Next()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, interval)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	testCases := []struct {
+		name        string
+		initial     time.Duration
+		maxInterval time.Duration
+		maxRetries  int
+		want        []time.Duration
+	}{
+		{
+			name:        "max retries",
+			initial:     time.Millisecond * 100,
+			maxInterval: time.Second * 10,
+			maxRetries:  4,
+			want: []time.Duration{
+				time.Millisecond * 100, time.Millisecond * 200, time.Millisecond * 400, time.Millisecond * 800,
+			},
+		},
+		{
+			name:        "max interval",
+			initial:     time.Millisecond * 100,
+			maxInterval: time.Millisecond * 300,
+			maxRetries:  4,
+			want: []time.Duration{
+				time.Millisecond * 100, time.Millisecond * 200, time.Millisecond * 300, time.Millisecond * 300,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := ExponentialBackoff(tc.initial, tc.maxInterval, tc.maxRetries)()
+			var got []time.Duration
+			for {
+				interval, ok := s.Next()
+				if !ok {
+					break
+				}
+				got = append(got, interval)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExponentialBackoff_Unlimited(t *testing.T) {
+	s := ExponentialBackoff(time.Millisecond, time.Hour, 0)()
+	var interval time.Duration
+	for i := 0; i < 1000; i++ {
+		var ok bool
+		interval, ok = s.Next()
+		assert.True(t, ok)
+	}
+	// 不会溢出
+	assert.Equal(t, time.Hour, interval)
+}
+
+func TestExponentialBackoff_NoCap(t *testing.T) {
+	// maxInterval <= 0 不设上限，而不是每一次都返回 0
+	s := ExponentialBackoff(time.Millisecond, 0, 0)()
+	want := time.Millisecond
+	for i := 0; i < 10; i++ {
+		interval, ok := s.Next()
+		assert.True(t, ok)
+		assert.Equal(t, want, interval)
+		want *= 2
+	}
+	for i := 0; i < 1000; i++ {
+		interval, ok := s.Next()
+		assert.True(t, ok)
+		// 不会溢出
+		assert.Greater(t, interval, time.Duration(0))
+	}
+}
+
+func TestDecorrelatedJitter_NoCap(t *testing.T) {
+	base := time.Millisecond * 10
+	s := DecorrelatedJitter(base, -1, 0)()
+	prev := base
+	for i := 0; i < 1000; i++ {
+		interval, ok := s.Next()
+		assert.True(t, ok)
+		assert.GreaterOrEqual(t, interval, base)
+		if prev < math.MaxInt64/3 {
+			assert.LessOrEqual(t, interval, prev*3)
+		}
+		prev = interval
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	base, maxInterval := time.Millisecond*10, time.Second
+	s := DecorrelatedJitter(base, maxInterval, 100)()
+	prev := base
+	for i := 0; i < 100; i++ {
+		interval, ok := s.Next()
+		assert.True(t, ok)
+		assert.GreaterOrEqual(t, interval, base)
+		assert.LessOrEqual(t, interval, min(prev*3, maxInterval))
+		prev = interval
+	}
+	_, ok := s.Next()
+	assert.False(t, ok)
+}
+
+func TestMaxElapsed(t *testing.T) {
+	s := MaxElapsed(FixedInterval(time.Millisecond*40, 0), time.Millisecond*100)()
+	interval, ok := s.Next()
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond*40, interval)
+	time.Sleep(time.Millisecond * 70)
+	// 再等 40ms 就超过 100ms 了
+	_, ok = s.Next()
+	assert.False(t, ok)
+}
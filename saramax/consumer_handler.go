@@ -1,14 +1,41 @@
 package saramax
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/IBM/sarama"
+	"github.com/Jared-lu/GXT/retry"
 )
 
 type Handler[T any] struct {
 	// 如何提供一个通用的日志输出，让调用方传入给我
 	//l  func(msg string, args ...interface{})
 	fn func(msg *sarama.ConsumerMessage, t T) error
+	// 每条消息创建一个新的重试策略
+	retry retry.Factory
+}
+
+// HandlerOption Handler 的配置
+type HandlerOption func(o *handlerOptions)
+
+type handlerOptions struct {
+	retry retry.Factory
+}
+
+// WithRetry 消费失败之后的重试策略，默认立刻重试两次
+// fn 返回 retry.Permanent 标记的 error 的时候不会重试
+func WithRetry(factory retry.Factory) HandlerOption {
+	return func(o *handlerOptions) {
+		o.retry = factory
+	}
+}
+
+func NewHandler[T any](fn func(msg *sarama.ConsumerMessage, t T) error, opts ...HandlerOption) *Handler[T] {
+	o := handlerOptions{retry: retry.FixedInterval(0, 2)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Handler[T]{fn: fn, retry: o.retry}
 }
 
 func (h *Handler[T]) Setup(session sarama.ConsumerGroupSession) error {
@@ -26,17 +53,13 @@ func (h *Handler[T]) ConsumeClaim(session sarama.ConsumerGroupSession, claim sar
 		if err != nil {
 			continue
 		}
-		// 是否重试与重试次数使用Option模式让用户决定
-		for i := 0; i < 3; i++ {
-			err = h.fn(msg, t)
-			if err == nil {
-				break
-			}
-			// 记录日志
-
-		}
+		// 再平衡的时候 session 的 ctx 会被取消，不再重试
+		err = retry.Do(session.Context(), h.retry(), func(ctx context.Context) error {
+			return h.fn(msg, t)
+		})
 		if err != nil {
 			// 重试次数达到上限
+			// 记录日志
 		} else {
 			session.MarkMessage(msg, "")
 		}
@@ -1,7 +1,7 @@
 package GXT
 
 // Comparator 比较两个元素的大小
-// src < dst, return -1
+// src < dst, 返回负数，一般是 -1
 // src == dst, return 0
-// src > dst, return 1
+// src > dst, 返回正数，一般是 1
 type Comparator[T any] func(src T, dst T) int